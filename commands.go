@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+)
+
+const commandUsage = "usage: chirpy [bootstrap-admin EMAIL]"
+
+// runCommand handles the one-off commands the server binary accepts in place
+// of serving, such as `chirpy bootstrap-admin alice@example.com`.
+func runCommand(ctx context.Context, db *database.Queries, args []string) error {
+	switch args[0] {
+	case "bootstrap-admin":
+		if len(args) != 2 {
+			return errors.New(commandUsage)
+		}
+		return bootstrapAdmin(ctx, db, args[1])
+	default:
+		return errors.New(commandUsage)
+	}
+}
+
+// bootstrapAdmin promotes an existing account to admin. It only works while
+// there are no admins: from then on roles are managed through the admin API.
+func bootstrapAdmin(ctx context.Context, db *database.Queries, email string) error {
+	admins, err := db.CountAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return errors.New("an admin already exists; grant roles with PUT /admin/users/{userID}/role")
+	}
+	dbUser, err := db.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no user with email %s; sign up first", email)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = db.SetUserRole(ctx, database.SetUserRoleParams{
+		Role:      string(RoleAdmin),
+		UpdatedAt: time.Now().UTC(),
+		ID:        dbUser.ID,
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("Admin bootstrapped", "user_id", dbUser.ID)
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+const (
+	accountDeletionGracePeriod = 30 * 24 * time.Hour
+	dataExportExpirationTime   = 7 * 24 * time.Hour
+	dataExportRetryAfter       = 30 * time.Second
+	// An export still unfinished after this long was most likely lost to a
+	// restart, so a new one is queued instead of waiting on it forever.
+	dataExportTimeout = time.Hour
+)
+
+const (
+	dataExportStatusPending = "pending"
+	dataExportStatusRunning = "running"
+	dataExportStatusReady   = "ready"
+	dataExportStatusFailed  = "failed"
+)
+
+// handlerDeleteAccount schedules the authenticated user's account for deletion
+// once the grace period has passed and signs out every session. The purge job
+// does the actual delete; until then the user can log in and cancel.
+func (cfg *apiConfig) handlerDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	if !cfg.checkCurrentPassword(w, r, dbUser, params.Password, "Password is incorrect") {
+		return
+	}
+
+	now := time.Now().UTC()
+	deletionScheduledAt := now.Add(accountDeletionGracePeriod)
+	err = cfg.db.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+		DeletionScheduledAt: sqlNullTime(deletionScheduledAt),
+		UpdatedAt:           now,
+		ID:                  userID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	err = revokeOtherCredentials(r.Context(), cfg.db, userID, "")
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	cfg.sendMailAsync(r.Context(), mailer.Message{
+		To:      dbUser.Email,
+		Subject: "Your Chirpy account will be deleted",
+		Body: "Your Chirpy account is scheduled for deletion on " +
+			deletionScheduledAt.Format("2 January 2006") + ".\n\n" +
+			"Log in before then and cancel the deletion if you change your mind.\n",
+	})
+
+	type response struct {
+		DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+	}
+	writeJSONResponse(w, http.StatusAccepted, response{
+		DeletionScheduledAt: deletionScheduledAt,
+	})
+}
+
+func (cfg *apiConfig) handlerCancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	err = cfg.db.CancelUserDeletion(r.Context(), database.CancelUserDeletionParams{
+		UpdatedAt: time.Now().UTC(),
+		ID:        userID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+// handlerExportAccount serves the user's latest data export if it is ready.
+// Otherwise it makes sure one is queued for the export job and answers 202, so
+// clients poll until the archive is available.
+func (cfg *apiConfig) handlerExportAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+
+	dbExport, err := cfg.db.GetLatestDataExportByUserID(r.Context(), userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		write500Error(w)
+		return
+	}
+	now := time.Now().UTC()
+	switch {
+	case err == nil && dbExport.Status == dataExportStatusReady && dbExport.ExpiresAt.Time.After(now):
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.zip"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write(dbExport.Archive)
+		return
+	case err == nil && (dbExport.Status == dataExportStatusPending || dbExport.Status == dataExportStatusRunning) &&
+		now.Sub(dbExport.CreatedAt) < dataExportTimeout:
+	default:
+		dbExport, err = cfg.db.CreateDataExport(r.Context(), database.CreateDataExportParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UserID:    userID,
+		})
+		if err != nil {
+			write500Error(w)
+			return
+		}
+	}
+
+	type response struct {
+		ID        uuid.UUID `json:"id"`
+		Status    string    `json:"status"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(dataExportRetryAfter)))
+	writeJSONResponse(w, http.StatusAccepted, response{
+		ID:        dbExport.ID,
+		Status:    dbExport.Status,
+		CreatedAt: dbExport.CreatedAt,
+	})
+}
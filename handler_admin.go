@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// atLeast reports whether role grants everything min does. Unknown roles
+// grant nothing.
+func (role Role) atLeast(min Role) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[min]
+}
+
+// outranks reports whether someone with role may act on an account with
+// other: moderators can act on users, admins on users and moderators.
+func (role Role) outranks(other Role) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank > roleRanks[other]
+}
+
+const (
+	EventTypeAdminGranted EventType = "admin.granted"
+	EventTypeAdminRevoked EventType = "admin.revoked"
+)
+
+const (
+	defaultAdminPageSize    = 50
+	maxAdminPageSize        = 100
+	maxSuspensionReasonSize = 500
+	// maxSuspensionHours is about ten years; anything longer may as well be
+	// indefinite, and far larger values overflow time.Duration.
+	maxSuspensionHours = 10 * 365 * 24
+)
+
+// isSuspended reports whether the account is suspended at now. Suspensions
+// without an end date last until lifted.
+func isSuspended(dbUser database.User, now time.Time) bool {
+	if !dbUser.SuspendedAt.Valid {
+		return false
+	}
+	return !dbUser.SuspendedUntil.Valid || dbUser.SuspendedUntil.Time.After(now)
+}
+
+func writeSuspendedError(w http.ResponseWriter, dbUser database.User) {
+	type response struct {
+		Error          string     `json:"error"`
+		Reason         string     `json:"reason,omitempty"`
+		SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	}
+	res := response{
+		Error:  "Account is suspended",
+		Reason: dbUser.SuspensionReason,
+	}
+	if dbUser.SuspendedUntil.Valid {
+		res.SuspendedUntil = &dbUser.SuspendedUntil.Time
+	}
+	writeJSONResponse(w, http.StatusForbidden, res)
+}
+
+type AdminUser struct {
+	User
+	Suspended        bool       `json:"suspended"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+}
+
+func adminUserResponse(dbUser database.User) AdminUser {
+	user := AdminUser{
+		User:      userResponse(dbUser),
+		Suspended: isSuspended(dbUser, time.Now().UTC()),
+	}
+	if user.Suspended {
+		user.SuspendedAt = &dbUser.SuspendedAt.Time
+		if dbUser.SuspendedUntil.Valid {
+			user.SuspendedUntil = &dbUser.SuspendedUntil.Time
+		}
+		user.SuspensionReason = dbUser.SuspensionReason
+	}
+	return user
+}
+
+// actorHandler is a handler behind middlewareRequireRole, given the account
+// making the request.
+type actorHandler func(w http.ResponseWriter, r *http.Request, actor database.User)
+
+// middlewareRequireRole lets through signed-in users holding at least role
+// whose account isn't suspended. Only login sessions count: tokens issued to
+// scripts or third-party apps never reach the admin API.
+func (cfg *apiConfig) middlewareRequireRole(role Role, next actorHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := cfg.authenticate(r)
+		if err != nil {
+			write401Error(w)
+			return
+		}
+		actor, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			write401Error(w)
+			return
+		}
+		if !Role(actor.Role).atLeast(role) || isSuspended(actor, time.Now().UTC()) {
+			slog.WarnContext(r.Context(), "Admin access denied", "role", actor.Role, "required", role)
+			write403Error(w)
+			return
+		}
+		next(w, r, actor)
+	}
+}
+
+// adminTarget loads the user named by the userID path value, writing a 404
+// if there is none.
+func (cfg *apiConfig) adminTarget(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		write404Error(w)
+		return database.User{}, false
+	}
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		write404Error(w)
+		return database.User{}, false
+	}
+	if err != nil {
+		write500Error(w)
+		return database.User{}, false
+	}
+	return dbUser, true
+}
+
+// handlerAdminSearchUsers lists users whose email or handle contains q, or
+// all users when q is empty, oldest first.
+func (cfg *apiConfig) handlerAdminSearchUsers(w http.ResponseWriter, r *http.Request, actor database.User) {
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	dbUsers, err := cfg.db.SearchUsers(r.Context(), database.SearchUsersParams{
+		Query:     r.URL.Query().Get("q"),
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	res := make([]AdminUser, len(dbUsers))
+	for i, dbUser := range dbUsers {
+		res[i] = adminUserResponse(dbUser)
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+func (cfg *apiConfig) handlerAdminGetUser(w http.ResponseWriter, r *http.Request, actor database.User) {
+	dbUser, ok := cfg.adminTarget(w, r)
+	if !ok {
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, adminUserResponse(dbUser))
+}
+
+func (cfg *apiConfig) handlerAdminSetRole(w http.ResponseWriter, r *http.Request, actor database.User) {
+	type parameters struct {
+		Role Role `json:"role"`
+	}
+	params := parameters{}
+	err := DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, ok := roleRanks[params.Role]; !ok {
+		writeError(w, http.StatusBadRequest, "Role must be one of user, moderator or admin")
+		return
+	}
+	dbUser, ok := cfg.adminTarget(w, r)
+	if !ok {
+		return
+	}
+	// Demoting yourself could leave no admin to undo it.
+	if dbUser.ID == actor.ID {
+		writeError(w, http.StatusBadRequest, "You can't change your own role")
+		return
+	}
+	dbUser, err = cfg.db.SetUserRole(r.Context(), database.SetUserRoleParams{
+		Role:      string(params.Role),
+		UpdatedAt: time.Now().UTC(),
+		ID:        dbUser.ID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	slog.InfoContext(r.Context(), "User role changed", "target_user_id", dbUser.ID, "role", params.Role)
+	writeJSONResponse(w, http.StatusOK, adminUserResponse(dbUser))
+}
+
+// handlerAdminSuspendUser blocks an account from signing in or posting, for
+// duration_hours (at most about ten years) or, if that is 0, until lifted.
+// Its sessions, personal access tokens and OAuth consents are revoked; access
+// tokens already issued run out within the hour.
+func (cfg *apiConfig) handlerAdminSuspendUser(w http.ResponseWriter, r *http.Request, actor database.User) {
+	type parameters struct {
+		Reason        string `json:"reason"`
+		DurationHours int    `json:"duration_hours"`
+	}
+	params := parameters{}
+	err := DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if utf8.RuneCountInString(params.Reason) > maxSuspensionReasonSize {
+		writeError(w, http.StatusBadRequest, "Reason must be at most 500 characters")
+		return
+	}
+	err = validateSuspensionHours(params.DurationHours)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dbUser, ok := cfg.adminTarget(w, r)
+	if !ok {
+		return
+	}
+	if !Role(actor.Role).outranks(Role(dbUser.Role)) {
+		write403Error(w)
+		return
+	}
+
+	err = cfg.inTx(r.Context(), func(qtx *database.Queries) error {
+		dbUser, err = suspendUser(r.Context(), qtx, dbUser.ID, params.Reason, params.DurationHours)
+		return err
+	})
+	if err != nil {
+		logRequestError(r, "Error suspending user", err)
+		write500Error(w)
+		return
+	}
+	slog.InfoContext(r.Context(), "User suspended", "target_user_id", dbUser.ID, "duration_hours", params.DurationHours)
+	writeJSONResponse(w, http.StatusOK, adminUserResponse(dbUser))
+}
+
+func validateSuspensionHours(hours int) error {
+	if hours < 0 || hours > maxSuspensionHours {
+		return fmt.Errorf("duration_hours must be between 0 and %d", maxSuspensionHours)
+	}
+	return nil
+}
+
+// suspendUser suspends an account for durationHours, which must have passed
+// validateSuspensionHours, or indefinitely if that is 0, and signs it out
+// everywhere. Call it in a transaction so that a failure part way doesn't
+// leave a suspended account signed in.
+func suspendUser(ctx context.Context, db *database.Queries, userID uuid.UUID, reason string, durationHours int) (database.User, error) {
+	now := time.Now().UTC()
+	suspendedUntil := sql.NullTime{}
+	if durationHours > 0 {
+		suspendedUntil = sqlNullTime(now.Add(time.Duration(durationHours) * time.Hour))
+	}
+	dbUser, err := db.SuspendUser(ctx, database.SuspendUserParams{
+		SuspendedAt:      sqlNullTime(now),
+		SuspendedUntil:   suspendedUntil,
+		SuspensionReason: reason,
+		UpdatedAt:        now,
+		ID:               userID,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	err = revokeOtherCredentials(ctx, db, userID, "")
+	if err != nil {
+		return database.User{}, err
+	}
+	return dbUser, nil
+}
+
+func (cfg *apiConfig) handlerAdminUnsuspendUser(w http.ResponseWriter, r *http.Request, actor database.User) {
+	dbUser, ok := cfg.adminTarget(w, r)
+	if !ok {
+		return
+	}
+	if !Role(actor.Role).outranks(Role(dbUser.Role)) {
+		write403Error(w)
+		return
+	}
+	dbUser, err := cfg.db.UnsuspendUser(r.Context(), database.UnsuspendUserParams{
+		UpdatedAt: time.Now().UTC(),
+		ID:        dbUser.ID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	slog.InfoContext(r.Context(), "User unsuspended", "target_user_id", dbUser.ID)
+	writeJSONResponse(w, http.StatusOK, adminUserResponse(dbUser))
+}
+
+// handlerAdminSetChirpyRed grants or takes away Chirpy Red outside of Polka,
+// e.g. for support cases. The change shows up in the subscription history.
+func (cfg *apiConfig) handlerAdminSetChirpyRed(w http.ResponseWriter, r *http.Request, actor database.User) {
+	type parameters struct {
+		IsChirpyRed bool `json:"is_chirpy_red"`
+	}
+	params := parameters{}
+	err := DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	dbUser, ok := cfg.adminTarget(w, r)
+	if !ok {
+		return
+	}
+	dbUser, err = cfg.db.UpdateUserChirpyRedByUserID(r.Context(), database.UpdateUserChirpyRedByUserIDParams{
+		IsChirpyRed: params.IsChirpyRed,
+		ID:          dbUser.ID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	event := EventTypeAdminRevoked
+	if params.IsChirpyRed {
+		event = EventTypeAdminGranted
+	}
+	err = cfg.db.CreateSubscriptionEvent(r.Context(), database.CreateSubscriptionEventParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UserID:    dbUser.ID,
+		Event:     string(event),
+	})
+	if err != nil {
+		logRequestError(r, "Error recording subscription event", err)
+	}
+	slog.InfoContext(r.Context(), "Chirpy Red changed by admin", "target_user_id", dbUser.ID, "is_chirpy_red", params.IsChirpyRed)
+	writeJSONResponse(w, http.StatusOK, adminUserResponse(dbUser))
+}
+
+func (cfg *apiConfig) handlerAdminDeleteChirp(w http.ResponseWriter, r *http.Request, actor database.User) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	dbChirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		write404Error(w)
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	err = cfg.db.DeleteChirpByID(r.Context(), dbChirp.ID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	cfg.publishChirpEvent(r, ChirpEventDeleted, dbChirp)
+	slog.InfoContext(r.Context(), "Chirp deleted by moderator", "chirp_id", dbChirp.ID, "author_id", dbChirp.UserID)
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+func queryInt(r *http.Request, key string, fallback int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/blob"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/imaging"
+	"github.com/google/uuid"
+)
+
+type AttachmentStatus string
+
+const (
+	AttachmentStatusPending    AttachmentStatus = "pending"
+	AttachmentStatusProcessing AttachmentStatus = "processing"
+	AttachmentStatusReady      AttachmentStatus = "ready"
+	AttachmentStatusFailed     AttachmentStatus = "failed"
+)
+
+const (
+	maxAttachmentSize      = 5 << 20
+	maxAttachmentsPerChirp = 4
+	// maxAttachmentPixels caps what an upload may decode to, about 160 MB of
+	// RGBA, whatever its file size.
+	maxAttachmentPixels = 40_000_000
+	// attachmentRetention is how long an upload may wait to be attached to a
+	// chirp before the purge job deletes it. Attachments of deleted chirps
+	// are deleted as soon as they are that old too.
+	attachmentRetention = 24 * time.Hour
+	// staleAttachmentBatchSize matches the LIMIT of GetStaleAttachments.
+	staleAttachmentBatchSize = 100
+)
+
+// attachmentExtensions maps the content types uploads may have, as sniffed
+// from their first bytes, to the extension their blobs are stored with.
+var attachmentExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type attachmentVariantSize struct {
+	Name    string
+	MaxSize int
+}
+
+// attachmentVariantSizes are the thumbnails made of every image, each no
+// longer than MaxSize on either side. Sizes the image already fits in are
+// skipped: the original serves for those.
+var attachmentVariantSizes = []attachmentVariantSize{
+	{Name: "small", MaxSize: 320},
+	{Name: "medium", MaxSize: 640},
+	{Name: "large", MaxSize: 1280},
+}
+
+var errInvalidAttachments = errors.New("invalid attachments")
+
+// Attachment describes an upload. Until it has been processed, its status is
+// pending or processing and it has no URL: handlerGetBlob doesn't serve the
+// file as uploaded, metadata and all.
+type Attachment struct {
+	ID          uuid.UUID           `json:"id"`
+	Status      string              `json:"status"`
+	URL         *string             `json:"url"`
+	ContentType string              `json:"content_type"`
+	Size        int64               `json:"size"`
+	Width       *int32              `json:"width"`
+	Height      *int32              `json:"height"`
+	Blurhash    *string             `json:"blurhash"`
+	Variants    []AttachmentVariant `json:"variants"`
+}
+
+type AttachmentVariant struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       int32  `json:"width"`
+	Height      int32  `json:"height"`
+	Size        int64  `json:"size"`
+}
+
+func (cfg *apiConfig) attachmentResponse(dbAttachment database.Attachment, dbVariants []database.AttachmentVariant) Attachment {
+	res := Attachment{
+		ID:          dbAttachment.ID,
+		Status:      dbAttachment.Status,
+		ContentType: dbAttachment.ContentType,
+		Size:        dbAttachment.Size,
+		Variants:    make([]AttachmentVariant, len(dbVariants)),
+	}
+	if dbAttachment.Status == string(AttachmentStatusReady) {
+		url := cfg.blobs.URL(dbAttachment.StorageKey)
+		res.URL = &url
+	}
+	if dbAttachment.Width.Valid && dbAttachment.Height.Valid {
+		res.Width = &dbAttachment.Width.Int32
+		res.Height = &dbAttachment.Height.Int32
+	}
+	if dbAttachment.Blurhash.Valid {
+		res.Blurhash = &dbAttachment.Blurhash.String
+	}
+	for i, dbVariant := range dbVariants {
+		res.Variants[i] = AttachmentVariant{
+			Name:        dbVariant.Name,
+			URL:         cfg.blobs.URL(dbVariant.StorageKey),
+			ContentType: dbVariant.ContentType,
+			Width:       dbVariant.Width,
+			Height:      dbVariant.Height,
+			Size:        dbVariant.Size,
+		}
+	}
+	return res
+}
+
+// chirpResponses is chirpResponse for chirps whose attachments the response
+// includes, loaded in one query.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, dbChirps []database.Chirp) ([]Chirp, error) {
+	chirpIDs := make([]uuid.UUID, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirpIDs[i] = dbChirp.ID
+	}
+	attachments, err := cfg.chirpAttachments(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		res[i] = chirpResponse(dbChirp)
+		if chirpAttachments, ok := attachments[dbChirp.ID]; ok {
+			res[i].Attachments = chirpAttachments
+		}
+	}
+	return res, nil
+}
+
+// chirpAttachments returns the attachments of each chirp that has any, in
+// the order they were given when it was posted, smallest variant first.
+func (cfg *apiConfig) chirpAttachments(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID][]Attachment, error) {
+	attachments := map[uuid.UUID][]Attachment{}
+	if len(chirpIDs) == 0 {
+		return attachments, nil
+	}
+	dbAttachments, err := cfg.db.GetAttachmentsByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := cfg.attachmentVariants(ctx, dbAttachments)
+	if err != nil {
+		return nil, err
+	}
+	for _, dbAttachment := range dbAttachments {
+		chirpID := dbAttachment.ChirpID.UUID
+		attachments[chirpID] = append(attachments[chirpID], cfg.attachmentResponse(dbAttachment, variants[dbAttachment.ID]))
+	}
+	return attachments, nil
+}
+
+func (cfg *apiConfig) attachmentVariants(ctx context.Context, dbAttachments []database.Attachment) (map[uuid.UUID][]database.AttachmentVariant, error) {
+	variants := map[uuid.UUID][]database.AttachmentVariant{}
+	if len(dbAttachments) == 0 {
+		return variants, nil
+	}
+	attachmentIDs := make([]uuid.UUID, len(dbAttachments))
+	for i, dbAttachment := range dbAttachments {
+		attachmentIDs[i] = dbAttachment.ID
+	}
+	dbVariants, err := cfg.db.GetAttachmentVariantsByAttachmentIDs(ctx, attachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, dbVariant := range dbVariants {
+		variants[dbVariant.AttachmentID] = append(variants[dbVariant.AttachmentID], dbVariant)
+	}
+	return variants, nil
+}
+
+// checkAttachments makes sure attachmentIDs are at most
+// maxAttachmentsPerChirp distinct uploads of userID's that aren't attached
+// to a chirp yet and didn't fail processing. Pending ones are fine: chirps
+// show them once they are ready.
+func (cfg *apiConfig) checkAttachments(ctx context.Context, userID uuid.UUID, attachmentIDs []uuid.UUID) error {
+	if len(attachmentIDs) == 0 {
+		return nil
+	}
+	if len(attachmentIDs) > maxAttachmentsPerChirp {
+		return errInvalidAttachments
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, attachmentID := range attachmentIDs {
+		if seen[attachmentID] {
+			return errInvalidAttachments
+		}
+		seen[attachmentID] = true
+	}
+	count, err := cfg.db.CountUnattachedAttachments(ctx, database.CountUnattachedAttachmentsParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Ids:    attachmentIDs,
+	})
+	if err != nil {
+		return err
+	}
+	if count != int64(len(attachmentIDs)) {
+		return errInvalidAttachments
+	}
+	return nil
+}
+
+// handlerUploadAttachment stores an image sent as the "file" field of a
+// multipart form. Its content type is sniffed from the file itself; whatever
+// the client claims is ignored. Images that would decode to more than
+// maxAttachmentPixels are rejected up front; the rest are processed in the
+// background by processAttachments. The returned ID can then be passed in
+// the attachment_ids of a new chirp; uploads not attached within
+// attachmentRetention are deleted.
+func (cfg *apiConfig) handlerUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticateScope(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	if !dbUser.EmailVerified {
+		writeError(w, http.StatusForbidden, "Email address must be verified before uploading attachments")
+		return
+	}
+	if isSuspended(dbUser, time.Now().UTC()) {
+		writeSuspendedError(w, dbUser)
+		return
+	}
+
+	// Leave room for the multipart headers around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+64<<10)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Request must be multipart/form-data")
+		return
+	}
+	var data []byte
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, "Missing file")
+			return
+		}
+		if err != nil {
+			writeUploadError(w, r, err)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		data, err = io.ReadAll(io.LimitReader(part, maxAttachmentSize+1))
+		if err != nil {
+			writeUploadError(w, r, err)
+			return
+		}
+		break
+	}
+	if len(data) == 0 {
+		writeError(w, http.StatusBadRequest, "File is empty")
+		return
+	}
+	if len(data) > maxAttachmentSize {
+		writeError(w, http.StatusRequestEntityTooLarge, "File must be at most 5 MB")
+		return
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := attachmentExtensions[contentType]
+	if !ok {
+		writeError(w, http.StatusUnsupportedMediaType, "File must be a JPEG, PNG, GIF or WebP image")
+		return
+	}
+	_, _, err = imaging.CheckSize(data, maxAttachmentPixels)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		writeError(w, http.StatusUnprocessableEntity, "Image dimensions are too large")
+		return
+	}
+	if err != nil {
+		logRequestWarn(r, "Error reading image", err)
+		writeError(w, http.StatusUnprocessableEntity, "File is not a valid image")
+		return
+	}
+
+	// Uploads stay under incoming/ until processing has stripped them of
+	// their metadata.
+	attachmentID := uuid.New()
+	storageKey := "incoming/" + attachmentID.String() + extension
+	err = cfg.blobs.Put(r.Context(), storageKey, bytes.NewReader(data), contentType)
+	if err != nil {
+		logRequestError(r, "Error storing attachment", err)
+		write500Error(w)
+		return
+	}
+	dbAttachment, err := cfg.db.CreateAttachment(r.Context(), database.CreateAttachmentParams{
+		ID:          attachmentID,
+		UserID:      uuid.NullUUID{UUID: userID, Valid: true},
+		StorageKey:  storageKey,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		if deleteErr := cfg.blobs.Delete(r.Context(), storageKey); deleteErr != nil {
+			logRequestError(r, "Error deleting orphaned attachment", deleteErr)
+		}
+		write500Error(w)
+		return
+	}
+	writeJSONResponse(w, http.StatusCreated, cfg.attachmentResponse(dbAttachment, nil))
+}
+
+func writeUploadError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeError(w, http.StatusRequestEntityTooLarge, "File must be at most 5 MB")
+		return
+	}
+	logRequestWarn(r, "Error reading upload", err)
+	writeError(w, http.StatusBadRequest, "Invalid multipart body")
+}
+
+// handlerGetBlob serves the stored files of processed attachments, as long
+// as they haven't been attached yet or their chirp is still visible. Raw
+// uploads, and attachments of hidden, removed or deleted chirps, are not
+// found.
+func (cfg *apiConfig) handlerGetBlob(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	contentType, err := cfg.db.GetServableBlobContentType(r.Context(), key)
+	if errors.Is(err, sql.ErrNoRows) {
+		write404Error(w)
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	body, err := cfg.blobs.Get(r.Context(), key)
+	if errors.Is(err, blob.ErrNotFound) {
+		write404Error(w)
+		return
+	}
+	if err != nil {
+		logRequestError(r, "Error reading blob", err)
+		write500Error(w)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Short enough that chirps taken down stop being served soon after.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", time.Time{}, seeker)
+		return
+	}
+	io.Copy(w, body)
+}
@@ -3,13 +3,21 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/dmytrochumakov/chirpy/internal/auth"
 	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/logging"
+	"github.com/dmytrochumakov/chirpy/internal/metrics"
+	"github.com/google/uuid"
 )
 
+const mfaTokenExpirationTime = 5 * time.Minute
+
 func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	type requestParams struct {
 		Password string `json:"password"`
@@ -19,65 +27,268 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&reqParams)
 	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
 		write500Error(w)
 		return
 	}
+	ip := cfg.clientIP(r)
+	wait, err := cfg.loginGuard.Check(r.Context(), reqParams.Email, ip)
+	if err != nil {
+		logRequestError(r, "Error checking login lockout", err)
+	}
+	if wait > 0 {
+		slog.InfoContext(r.Context(), "Login blocked", "wait", wait)
+		cfg.metrics.ObserveLogin(metrics.LoginResultBlocked)
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+		writeError(w, http.StatusTooManyRequests, "Too many failed login attempts")
+		return
+	}
+
 	dbUser, err := cfg.db.GetUserByEmail(r.Context(), reqParams.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		cfg.metrics.ObserveLogin(metrics.LoginResultError)
+		write500Error(w)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		auth.CheckPasswordUnknownUser(r.Context(), reqParams.Password)
+		slog.InfoContext(r.Context(), "Login failed: unknown user")
+		cfg.metrics.ObserveLogin(metrics.LoginResultUnknownUser)
+		cfg.recordLoginFailure(w, r, reqParams.Email, ip)
+		return
+	}
+	outdated, err := auth.CheckPasswordHash(r.Context(), dbUser.HashedPassword, reqParams.Password)
 	if err != nil {
-		write404Error(w)
+		logging.SetUserID(r.Context(), dbUser.ID.String())
+		logRequestWarn(r, "Login failed: wrong password", err)
+		cfg.metrics.ObserveLogin(metrics.LoginResultWrongPassword)
+		cfg.recordLoginFailure(w, r, reqParams.Email, ip)
+		return
+	}
+	if outdated {
+		cfg.rehashPassword(r, dbUser, reqParams.Password)
+	}
+	if isSuspended(dbUser, time.Now().UTC()) {
+		slog.InfoContext(r.Context(), "Login refused: account suspended")
+		writeSuspendedError(w, dbUser)
 		return
 	}
-	err = auth.CheckPasswordHash(dbUser.HashedPassword, reqParams.Password)
+	if dbUser.TotpEnabled {
+		cfg.startMFAChallenge(w, r, dbUser)
+		return
+	}
+	cfg.completeLogin(w, r, dbUser)
+}
+
+// rehashPassword replaces a hash written by an older scheme or with weaker
+// parameters. It is best effort: the login goes ahead either way, and the
+// update is skipped if the password changed in the meantime.
+func (cfg *apiConfig) rehashPassword(r *http.Request, dbUser database.User, password string) {
+	hashedPassword, err := auth.HashPassword(r.Context(), password)
 	if err != nil {
-		write401Error(w)
+		logRequestError(r, "Error rehashing password", err)
+		return
+	}
+	err = cfg.db.RehashUserPassword(r.Context(), database.RehashUserPasswordParams{
+		NewHash: hashedPassword,
+		ID:      dbUser.ID,
+		OldHash: dbUser.HashedPassword,
+	})
+	if err != nil {
+		logRequestError(r, "Error storing rehashed password", err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Password hash upgraded")
+}
+
+// startMFAChallenge answers a correct password for a user with two-factor
+// authentication enabled. The client exchanges the returned token and a TOTP
+// or recovery code at POST /api/login/mfa for the usual token pair.
+func (cfg *apiConfig) startMFAChallenge(w http.ResponseWriter, r *http.Request, dbUser database.User) {
+	logging.SetUserID(r.Context(), dbUser.ID.String())
+	mfaToken, err := auth.MakeMFAToken(dbUser.ID, cfg.jwtSecret, mfaTokenExpirationTime)
+	if err != nil {
+		logRequestError(r, "Error creating MFA token", err)
+		cfg.metrics.ObserveLogin(metrics.LoginResultError)
+		write500Error(w)
 		return
 	}
+	cfg.metrics.ObserveLogin(metrics.LoginResultMFARequired)
+
+	type response struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}
+	writeJSONResponse(w, http.StatusOK, response{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	})
+}
+
+func (cfg *apiConfig) completeLogin(w http.ResponseWriter, r *http.Request, dbUser database.User) {
+	err := cfg.loginGuard.RecordSuccess(r.Context(), dbUser.Email)
+	if err != nil {
+		logRequestError(r, "Error clearing login failures", err)
+	}
+
+	accessToken, refreshToken, err := cfg.issueTokens(r, dbUser.ID)
+	if err != nil {
+		logRequestError(r, "Error issuing tokens", err)
+		cfg.metrics.ObserveLogin(metrics.LoginResultError)
+		write500Error(w)
+		return
+	}
+
+	logging.SetUserID(r.Context(), dbUser.ID.String())
+	cfg.metrics.ObserveLogin(metrics.LoginResultSuccess)
+
+	type response struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	writeJSONResponse(w, 200, response{
+		User:         userResponse(dbUser),
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+// issueTokens creates an access token and a stored refresh token for userID.
+func (cfg *apiConfig) issueTokens(r *http.Request, userID uuid.UUID) (string, string, error) {
 	accessTokenExpirationTime := time.Hour
 
 	accessToken, err := auth.MakeJWT(
-		dbUser.ID,
+		userID,
 		cfg.jwtSecret,
 		accessTokenExpirationTime,
 	)
 	if err != nil {
-		write500Error(w)
-		return
+		return "", "", err
 	}
 
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		write500Error(w)
-		return
+		return "", "", err
 	}
 	dbRefreshToken, err := cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
 		Token:     refreshToken,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
-		UserID:    dbUser.ID,
+		UserID:    userID,
 		ExpiresAt: time.Now().Add(60 * 24 * time.Hour),
 		RevokedAt: sql.NullTime{},
 	})
 	if err != nil {
+		return "", "", err
+	}
+	return accessToken, dbRefreshToken.Token, nil
+}
+
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	params := parameters{}
+	err := DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
 		write500Error(w)
 		return
 	}
+	userID, err := auth.ValidateMFAToken(params.MFAToken, cfg.jwtSecret)
+	if err != nil {
+		logRequestWarn(r, "Invalid MFA token", err)
+		write401Error(w)
+		return
+	}
+	logging.SetUserID(r.Context(), userID.String())
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil || !dbUser.TotpEnabled {
+		write401Error(w)
+		return
+	}
 
-	type response struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
+	ip := cfg.clientIP(r)
+	wait, err := cfg.loginGuard.Check(r.Context(), dbUser.Email, ip)
+	if err != nil {
+		logRequestError(r, "Error checking login lockout", err)
 	}
-	writeJSONResponse(w, 200, response{
-		User: User{
-			ID:          dbUser.ID,
-			CreatedAt:   dbUser.CreatedAt,
-			UpdatedAt:   dbUser.UpdatedAt,
-			Email:       dbUser.Email,
-			IsChirpyRed: dbUser.IsChirpyRed,
-		},
-		Token:        accessToken,
-		RefreshToken: dbRefreshToken.Token,
-	})
+	if wait > 0 {
+		cfg.metrics.ObserveLogin(metrics.LoginResultBlocked)
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+		writeError(w, http.StatusTooManyRequests, "Too many failed login attempts")
+		return
+	}
+
+	if params.RecoveryCode != "" {
+		err = cfg.useRecoveryCode(r, dbUser, params.RecoveryCode)
+	} else {
+		err = cfg.verifyTOTPCode(r, dbUser, params.Code)
+	}
+	if errors.Is(err, auth.ErrInvalidTOTPCode) {
+		logRequestWarn(r, "Login failed: wrong MFA code", err)
+		cfg.metrics.ObserveLogin(metrics.LoginResultWrongMFACode)
+		err = cfg.loginGuard.RecordFailure(r.Context(), dbUser.Email, ip)
+		if err != nil {
+			logRequestError(r, "Error recording login failure", err)
+		}
+		writeError(w, http.StatusUnauthorized, "Invalid two-factor code")
+		return
+	}
+	if err != nil {
+		logRequestError(r, "Error verifying MFA code", err)
+		cfg.metrics.ObserveLogin(metrics.LoginResultError)
+		write500Error(w)
+		return
+	}
+	cfg.completeLogin(w, r, dbUser)
+}
+
+// recordLoginFailure answers every failed login the same way, whether or not
+// the email belongs to an account, so responses can't be used to enumerate users.
+func (cfg *apiConfig) recordLoginFailure(w http.ResponseWriter, r *http.Request, email, ip string) {
+	err := cfg.loginGuard.RecordFailure(r.Context(), email, ip)
+	if err != nil {
+		logRequestError(r, "Error recording login failure", err)
+	}
+	writeError(w, http.StatusUnauthorized, "Incorrect email or password")
+}
+
+// checkCurrentPassword asks a signed-in user for their password again before
+// a sensitive change. Wrong guesses count towards the same lockout as logins,
+// so a stolen access token can't be used to find the password. It writes the
+// error response and returns false if the password is wrong or the account is
+// locked out.
+func (cfg *apiConfig) checkCurrentPassword(w http.ResponseWriter, r *http.Request, dbUser database.User, password, message string) bool {
+	ip := cfg.clientIP(r)
+	wait, err := cfg.loginGuard.Check(r.Context(), dbUser.Email, ip)
+	if err != nil {
+		logRequestError(r, "Error checking login lockout", err)
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+		writeError(w, http.StatusTooManyRequests, "Too many failed password attempts")
+		return false
+	}
+
+	_, err = auth.CheckPasswordHash(r.Context(), dbUser.HashedPassword, password)
+	if err != nil {
+		logRequestWarn(r, "Password check failed", err)
+		err = cfg.loginGuard.RecordFailure(r.Context(), dbUser.Email, ip)
+		if err != nil {
+			logRequestError(r, "Error recording login failure", err)
+		}
+		writeError(w, http.StatusUnauthorized, message)
+		return false
+	}
+	err = cfg.loginGuard.RecordSuccess(r.Context(), dbUser.Email)
+	if err != nil {
+		logRequestError(r, "Error clearing login failures", err)
+	}
+	return true
 }
 
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
@@ -86,18 +297,26 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 	}
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
+		logRequestWarn(r, "Missing refresh token", err)
 		write401Error(w)
 		return
 	}
 
 	dbUser, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
 	if err != nil {
+		logRequestWarn(r, "Invalid refresh token", err)
 		write401Error(w)
 		return
 	}
+	logging.SetUserID(r.Context(), dbUser.ID.String())
+	if isSuspended(dbUser, time.Now().UTC()) {
+		writeSuspendedError(w, dbUser)
+		return
+	}
 
 	authToken, err := auth.MakeJWT(dbUser.ID, cfg.jwtSecret, time.Hour)
 	if err != nil {
+		logRequestError(r, "Error creating access token", err)
 		write401Error(w)
 		return
 	}
@@ -110,15 +329,18 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
+		logRequestWarn(r, "Missing refresh token", err)
 		write401Error(w)
 		return
 	}
 
 	dbUser, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
 	if err != nil {
+		logRequestWarn(r, "Invalid refresh token", err)
 		write401Error(w)
 		return
 	}
+	logging.SetUserID(r.Context(), dbUser.ID.String())
 
 	err = cfg.db.RevokeRefreshToken(r.Context(), database.RevokeRefreshTokenParams{
 		RevokedAt: sql.NullTime{
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerBlockUser hides the two users' chirps from each other, whoever is
+// reading. Muting, below, is one-sided and softer: it only takes the muted
+// user's chirps out of the muter's GET /api/chirps listing, and they still
+// show up when asked for with author_id. Both are filtered in SQL, so results
+// stay correct however they are sliced.
+func (cfg *apiConfig) handlerBlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.CreateUserBlock(r.Context(), database.CreateUserBlockParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.db.DeleteUserBlock(r.Context(), database.DeleteUserBlockParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerMuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.CreateUserMute(r.Context(), database.CreateUserMuteParams{
+		MuterID:   userID,
+		MutedID:   targetID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.db.DeleteUserMute(r.Context(), database.DeleteUserMuteParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+// relationTarget authenticates the request and resolves the userID path
+// value, a UUID or handle, to an existing user other than the caller.
+func (cfg *apiConfig) relationTarget(w http.ResponseWriter, r *http.Request) (userID, targetID uuid.UUID, ok bool) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return uuid.Nil, uuid.Nil, false
+	}
+	targetID, err = cfg.resolveUserID(r.Context(), r.PathValue("userID"))
+	if err == nil {
+		_, err = cfg.db.GetUserByID(r.Context(), targetID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		write404Error(w)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if err != nil {
+		write500Error(w)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if targetID == userID {
+		writeError(w, http.StatusBadRequest, "You can't do that to yourself")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, targetID, true
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"sort"
 	"time"
 
 	"github.com/dmytrochumakov/chirpy/internal/auth"
 	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/entities"
 	"github.com/google/uuid"
 )
 
@@ -23,49 +26,123 @@ type Chirp struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	Body      string    `json:"body"`
 	UserID    string    `json:"user_id"`
+	// Hidden is only ever true for the author: nobody else sees hidden chirps.
+	Hidden      bool              `json:"hidden,omitempty"`
+	Entities    []entities.Entity `json:"entities"`
+	Attachments []Attachment      `json:"attachments"`
+}
+
+// chirpResponse leaves out attachments, which chirpResponses loads.
+func chirpResponse(dbChirp database.Chirp) Chirp {
+	return Chirp{
+		ID:          dbChirp.ID,
+		CreatedAt:   dbChirp.CreatedAt,
+		UpdatedAt:   dbChirp.UpdatedAt,
+		Body:        dbChirp.Body,
+		UserID:      dbChirp.UserID.String(),
+		Hidden:      dbChirp.ModerationStatus == string(ModerationStatusHidden),
+		Entities:    entities.Parse(dbChirp.Body),
+		Attachments: []Attachment{},
+	}
+}
+
+// chirpViewer identifies who is reading chirps, so authors keep seeing their
+// own hidden chirps. Reading needs no token; one that doesn't check out is
+// treated as no token at all.
+func (cfg *apiConfig) chirpViewer(r *http.Request) uuid.NullUUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userID, err := cfg.validateScopedToken(r, token, auth.ScopeChirpsRead)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
 }
 
 func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
-		Body string `json:"body"`
+		Body          string      `json:"body"`
+		AttachmentIDs []uuid.UUID `json:"attachment_ids"`
 	}
 	reqBody := requestBody{}
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&reqBody)
 	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
 		write500Error(w)
 		return
 	}
-	token, err := auth.GetBearerToken(r.Header)
+	userID, err := cfg.authenticateScope(r, auth.ScopeChirpsWrite)
 	if err != nil {
-		write401Error(w)
+		writeAuthError(w, err)
 		return
 	}
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
 	if err != nil {
 		write401Error(w)
 		return
 	}
+	if !dbUser.EmailVerified {
+		writeError(w, http.StatusForbidden, "Email address must be verified before posting chirps")
+		return
+	}
+	if isSuspended(dbUser, time.Now().UTC()) {
+		writeSuspendedError(w, dbUser)
+		return
+	}
+
+	dbChirp, err := cfg.createChirp(r, userID, reqBody.Body, reqBody.AttachmentIDs)
+	if errors.Is(err, errInvalidAttachments) {
+		writeError(w, http.StatusBadRequest, "attachment_ids must be at most 4 of your uploads not yet attached to a chirp")
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	res, err := cfg.chirpResponses(r.Context(), []database.Chirp{dbChirp})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeJSONResponse(w, 201, res[0])
+}
 
+// createChirp saves a chirp the caller has checked userID may post, attaches
+// userID's uploads to it, indexes it and publishes it to live clients. It
+// returns errInvalidAttachments, before saving anything, if attachmentIDs
+// aren't uploads that can be attached.
+func (cfg *apiConfig) createChirp(r *http.Request, userID uuid.UUID, body string, attachmentIDs []uuid.UUID) (database.Chirp, error) {
+	err := cfg.checkAttachments(r.Context(), userID, attachmentIDs)
+	if err != nil {
+		return database.Chirp{}, err
+	}
 	dbChirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
-		Body:      reqBody.Body,
+		Body:      body,
 		UserID:    userID,
 	})
 	if err != nil {
-		write500Error(w)
-		return
+		return database.Chirp{}, err
 	}
-
-	writeJSONResponse(w, 201, Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    userID.String(),
-	})
+	if len(attachmentIDs) > 0 {
+		_, err = cfg.db.AttachAttachmentsToChirp(r.Context(), database.AttachAttachmentsToChirpParams{
+			ChirpID:    uuid.NullUUID{UUID: dbChirp.ID, Valid: true},
+			AttachedAt: sqlNullTime(dbChirp.CreatedAt),
+			Ids:        attachmentIDs,
+			UserID:     uuid.NullUUID{UUID: userID, Valid: true},
+		})
+		if err != nil {
+			logRequestError(r, "Error attaching attachments", err)
+		}
+	}
+	cfg.indexChirpEntities(r, dbChirp)
+	cfg.publishChirpEvent(r, ChirpEventCreated, dbChirp)
+	return dbChirp, nil
 }
 
 func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, r *http.Request) {
@@ -74,20 +151,15 @@ func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, r *http.Request
 		cfg.handlerGetChirpByUserID(w, r, authorID)
 		return
 	}
-	dbChirps, err := cfg.db.GetAllChirps(r.Context())
+	dbChirps, err := cfg.db.GetVisibleChirps(r.Context(), cfg.chirpViewer(r))
 	if err != nil {
 		write500Error(w)
 		return
 	}
-	res := make([]Chirp, len(dbChirps))
-	for i, dbChirp := range dbChirps {
-		res[i] = Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID.String(),
-		}
+	res, err := cfg.chirpResponses(r.Context(), dbChirps)
+	if err != nil {
+		write500Error(w)
+		return
 	}
 	sortType := r.URL.Query().Get("sort")
 	if sortType == string(SortTypeDESC) {
@@ -101,36 +173,41 @@ func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, r *http.Request
 func (cfg *apiConfig) handlerGetChirpByID(w http.ResponseWriter, r *http.Request) {
 	parsedUUID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
+		logRequestWarn(r, "Invalid chirp ID", err)
 		write500Error(w)
 		return
 	}
-	dbChirp, err := cfg.db.GetChirpByID(r.Context(), parsedUUID)
+	dbChirp, err := cfg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       parsedUUID,
+		ViewerID: cfg.chirpViewer(r),
+	})
 	if err != nil {
 		write404Error(w)
 		return
 	}
-	writeJSONResponse(w, 200, Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID.String(),
-	})
+	res, err := cfg.chirpResponses(r.Context(), []database.Chirp{dbChirp})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeJSONResponse(w, 200, res[0])
 }
 
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
+		logRequestWarn(r, "Invalid chirp ID", err)
 		write500Error(w)
 		return
 	}
 
 	authToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
+		logRequestWarn(r, "Missing bearer token", err)
 		write401Error(w)
 		return
 	}
-	userID, err := auth.ValidateJWT(authToken, cfg.jwtSecret)
+	userID, err := cfg.validateScopedToken(r, authToken, auth.ScopeChirpsWrite)
 	if err != nil {
 		write403Error(w)
 		return
@@ -149,30 +226,34 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		write500Error(w)
 		return
 	}
+	cfg.publishChirpEvent(r, ChirpEventDeleted, dbChirp)
 	writeStatusCodeResponse(w, http.StatusNoContent)
 }
 
 func (cfg *apiConfig) handlerGetChirpByUserID(w http.ResponseWriter, r *http.Request, userID string) {
-	userUUID, err := uuid.Parse(userID)
+	userUUID, err := cfg.resolveUserID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		write404Error(w)
+		return
+	}
 	if err != nil {
+		logRequestWarn(r, "Invalid author ID", err)
 		write500Error(w)
 		return
 	}
-	dbChirps, err := cfg.db.GetChirpsByUserID(r.Context(), userUUID)
+	dbChirps, err := cfg.db.GetVisibleChirpsByUserID(r.Context(), database.GetVisibleChirpsByUserIDParams{
+		UserID:   userUUID,
+		ViewerID: cfg.chirpViewer(r),
+	})
 	if err != nil {
 		write403Error(w)
 		return
 	}
 
-	res := make([]Chirp, len(dbChirps))
-	for i, dbChirp := range dbChirps {
-		res[i] = Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID.String(),
-		}
+	res, err := cfg.chirpResponses(r.Context(), dbChirps)
+	if err != nil {
+		write500Error(w)
+		return
 	}
 	sortType := r.URL.Query().Get("sort")
 	if sortType == string(SortTypeDESC) {
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+const emailVerificationTokenExpirationTime = 24 * time.Hour
+
+// sendEmailVerification mails a confirmation link to email. For a new account
+// email is the address it signed up with; for an email change it is the new
+// address, which only replaces the old one once the link is followed. Links
+// sent earlier stop working, so an old one can't switch the account back to
+// an address the user has since moved away from.
+func (cfg *apiConfig) sendEmailVerification(r *http.Request, userID uuid.UUID, email string) error {
+	token, err := auth.MakeOneTimeToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.db.InvalidateEmailVerificationTokensByUserID(r.Context(), database.InvalidateEmailVerificationTokensByUserIDParams{
+		UsedAt: sqlNullTime(time.Now().UTC()),
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	err = cfg.db.CreateEmailVerificationToken(r.Context(), database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		CreatedAt: time.Now().UTC(),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().UTC().Add(emailVerificationTokenExpirationTime),
+	})
+	if err != nil {
+		return err
+	}
+	cfg.sendMailAsync(r.Context(), mailer.Message{
+		To:      email,
+		Subject: "Confirm your email address for Chirpy",
+		Body: fmt.Sprintf(
+			"To confirm this email address for your Chirpy account, open:\n%s/verify-email?token=%s\n\n"+
+				"The link expires in 24 hours. If you didn't ask for this, you can ignore this email.\n",
+			cfg.baseURL, token,
+		),
+	})
+	return nil
+}
+
+func (cfg *apiConfig) handlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+	params := parameters{}
+	err := DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		write500Error(w)
+		return
+	}
+
+	now := time.Now().UTC()
+	verification, err := cfg.db.UseEmailVerificationToken(r.Context(), database.UseEmailVerificationTokenParams{
+		UsedAt:    sqlNullTime(now),
+		TokenHash: auth.HashToken(params.Token),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusBadRequest, "Invalid or expired verification token")
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	dbUser, err := cfg.db.ConfirmUserEmail(r.Context(), database.ConfirmUserEmailParams{
+		Email:     verification.Email,
+		UpdatedAt: now,
+		ID:        verification.UserID,
+	})
+	if isUniqueViolation(err) {
+		writeError(w, http.StatusConflict, userConflictMessage(err))
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	err = cfg.db.InvalidateEmailVerificationTokensByUserID(r.Context(), database.InvalidateEmailVerificationTokensByUserIDParams{
+		UsedAt: sqlNullTime(now),
+		UserID: dbUser.ID,
+	})
+	if err != nil {
+		logRequestError(r, "Error invalidating email verification tokens", err)
+	}
+	writeJSONResponse(w, http.StatusOK, userResponse(dbUser))
+}
+
+func (cfg *apiConfig) handlerResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	if dbUser.EmailVerified {
+		writeError(w, http.StatusConflict, "Email is already verified")
+		return
+	}
+	err = cfg.sendEmailVerification(r, dbUser.ID, dbUser.Email)
+	if err != nil {
+		logRequestError(r, "Error sending email verification", err)
+		write500Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusAccepted)
+}
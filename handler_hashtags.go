@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/entities"
+	"github.com/google/uuid"
+)
+
+type trendingWindow struct {
+	Name     string
+	Duration time.Duration
+}
+
+// trendingWindows are the sliding windows trending hashtags are ranked over.
+var trendingWindows = []trendingWindow{
+	{Name: "1h", Duration: time.Hour},
+	{Name: "24h", Duration: 24 * time.Hour},
+	{Name: "7d", Duration: 7 * 24 * time.Hour},
+}
+
+const (
+	defaultTrendingWindow = "24h"
+	maxTrendingHashtags   = 50
+)
+
+// indexChirpEntities records a new chirp's hashtags and mentions of existing
+// users, and notifies the users mentioned. The chirp is already saved, so
+// failures are only logged: the chirp is just missing from the hashtag
+// listing and trending, or someone misses a notification.
+func (cfg *apiConfig) indexChirpEntities(r *http.Request, dbChirp database.Chirp) {
+	if tags := entities.Hashtags(dbChirp.Body); len(tags) > 0 {
+		err := cfg.db.CreateChirpHashtags(r.Context(), database.CreateChirpHashtagsParams{
+			ChirpID:   dbChirp.ID,
+			Tags:      tags,
+			CreatedAt: dbChirp.CreatedAt,
+		})
+		if err != nil {
+			logRequestError(r, "Error indexing hashtags", err)
+		}
+	}
+	if handles := entities.Mentions(dbChirp.Body); len(handles) > 0 {
+		mentionedIDs, err := cfg.db.CreateChirpMentions(r.Context(), database.CreateChirpMentionsParams{
+			ChirpID: dbChirp.ID,
+			Handles: handles,
+		})
+		if err != nil {
+			logRequestError(r, "Error indexing mentions", err)
+		}
+		for _, mentionedID := range mentionedIDs {
+			err = cfg.notify(r.Context(), mentionedID, dbChirp.UserID, NotificationTypeMention, uuid.NullUUID{UUID: dbChirp.ID, Valid: true})
+			if err != nil {
+				logRequestError(r, "Error creating mention notification", err)
+			}
+		}
+	}
+}
+
+// handlerGetHashtagChirps lists chirps tagged with the hashtag, newest first.
+// The tag may be given with or without the #, in any case.
+func (cfg *apiConfig) handlerGetHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	dbChirps, err := cfg.db.GetVisibleChirpsByHashtag(r.Context(), database.GetVisibleChirpsByHashtagParams{
+		Tag:       entities.NormalizeHashtag(r.PathValue("tag")),
+		ViewerID:  cfg.chirpViewer(r),
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	res, err := cfg.chirpResponses(r.Context(), dbChirps)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+// handlerGetTrendingHashtags returns the latest ranking for the window query
+// parameter: 1h, 24h (the default) or 7d.
+func (cfg *apiConfig) handlerGetTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = defaultTrendingWindow
+	}
+	known := slices.ContainsFunc(trendingWindows, func(trending trendingWindow) bool {
+		return trending.Name == window
+	})
+	if !known {
+		writeError(w, http.StatusBadRequest, "window must be one of 1h, 24h or 7d")
+		return
+	}
+	dbHashtags, err := cfg.db.GetTrendingHashtags(r.Context(), window)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type hashtag struct {
+		Rank        int32  `json:"rank"`
+		Tag         string `json:"tag"`
+		ChirpCount  int64  `json:"chirp_count"`
+		AuthorCount int64  `json:"author_count"`
+	}
+	type response struct {
+		Window     string     `json:"window"`
+		ComputedAt *time.Time `json:"computed_at"`
+		Hashtags   []hashtag  `json:"hashtags"`
+	}
+	res := response{
+		Window:   window,
+		Hashtags: make([]hashtag, len(dbHashtags)),
+	}
+	for i, dbHashtag := range dbHashtags {
+		res.ComputedAt = &dbHashtag.ComputedAt
+		res.Hashtags[i] = hashtag{
+			Rank:        dbHashtag.Rank,
+			Tag:         dbHashtag.Tag,
+			ChirpCount:  dbHashtag.ChirpCount,
+			AuthorCount: dbHashtag.AuthorCount,
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
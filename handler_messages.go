@@ -0,0 +1,417 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxConversationMembers counts the creator too.
+const maxConversationMembers = 10
+
+type Conversation struct {
+	ID          uuid.UUID   `json:"id"`
+	MemberIDs   []uuid.UUID `json:"member_ids"`
+	UnreadCount int64       `json:"unread_count"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+type ConversationMember struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func messageResponse(dbMessage database.Message) Message {
+	return Message{
+		ID:             dbMessage.ID,
+		ConversationID: dbMessage.ConversationID,
+		SenderID:       dbMessage.SenderID,
+		Body:           dbMessage.Body,
+		CreatedAt:      dbMessage.CreatedAt,
+	}
+}
+
+// directConversationKey is the same for both members of a one-to-one
+// conversation, whoever started it.
+func directConversationKey(a, b uuid.UUID) string {
+	ids := []string{a.String(), b.String()}
+	slices.Sort(ids)
+	return strings.Join(ids, ":")
+}
+
+// canSendMessages holds senders of direct messages to the same rules as
+// authors of chirps, writing the error if they can't.
+func (cfg *apiConfig) canSendMessages(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		write401Error(w)
+		return false
+	}
+	if !dbUser.EmailVerified {
+		writeError(w, http.StatusForbidden, "Email address must be verified before sending messages")
+		return false
+	}
+	if isSuspended(dbUser, time.Now().UTC()) {
+		writeSuspendedError(w, dbUser)
+		return false
+	}
+	return true
+}
+
+// handlerCreateConversation starts a conversation with member_ids, UUIDs or
+// handles of the other members. With a single other member it returns the
+// existing one-to-one conversation, if there is one, with 200 instead of 201.
+// Nobody can be added to a conversation with someone they blocked or who
+// blocked them.
+func (cfg *apiConfig) handlerCreateConversation(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	type parameters struct {
+		MemberIDs []string `json:"member_ids"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	memberIDs := []uuid.UUID{}
+	for _, member := range params.MemberIDs {
+		memberID, err := cfg.resolveUserID(r.Context(), member)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "User not found: "+member)
+			return
+		}
+		if err != nil {
+			write500Error(w)
+			return
+		}
+		if memberID != userID && !slices.Contains(memberIDs, memberID) {
+			memberIDs = append(memberIDs, memberID)
+		}
+	}
+	if len(memberIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "A conversation needs at least one other member")
+		return
+	}
+	if len(memberIDs)+1 > maxConversationMembers {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("A conversation can have at most %d members", maxConversationMembers))
+		return
+	}
+	existing, err := cfg.db.CountUsersByIDs(r.Context(), memberIDs)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	if existing != int64(len(memberIDs)) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if !cfg.canSendMessages(w, r, userID) {
+		return
+	}
+	blocks, err := cfg.db.CountBlocksWithUsers(r.Context(), database.CountBlocksWithUsersParams{
+		UserID:   userID,
+		OtherIds: memberIDs,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	if blocks > 0 {
+		writeError(w, http.StatusForbidden, "You can't message some of these users")
+		return
+	}
+
+	var directKey sql.NullString
+	if len(memberIDs) == 1 {
+		directKey = sqlNullString(directConversationKey(userID, memberIDs[0]))
+		dbConversation, err := cfg.db.GetConversationByDirectKey(r.Context(), directKey.String)
+		if err == nil {
+			cfg.writeConversation(w, r, http.StatusOK, dbConversation)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			write500Error(w)
+			return
+		}
+	}
+	dbConversation, err := cfg.db.CreateConversation(r.Context(), database.CreateConversationParams{
+		ID:        uuid.New(),
+		DirectKey: directKey,
+		CreatedAt: time.Now().UTC(),
+		MemberIds: append(memberIDs, userID),
+	})
+	if isUniqueViolation(err) {
+		// The other member started the same conversation at the same time.
+		dbConversation, err = cfg.db.GetConversationByDirectKey(r.Context(), directKey.String)
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	cfg.writeConversation(w, r, http.StatusCreated, dbConversation)
+}
+
+func (cfg *apiConfig) writeConversation(w http.ResponseWriter, r *http.Request, statusCode int, dbConversation database.Conversation) {
+	dbMembers, err := cfg.db.GetConversationMembers(r.Context(), dbConversation.ID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type response struct {
+		ID        uuid.UUID            `json:"id"`
+		Members   []ConversationMember `json:"members"`
+		CreatedAt time.Time            `json:"created_at"`
+		UpdatedAt time.Time            `json:"updated_at"`
+	}
+	res := response{
+		ID:        dbConversation.ID,
+		Members:   make([]ConversationMember, len(dbMembers)),
+		CreatedAt: dbConversation.CreatedAt,
+		UpdatedAt: dbConversation.UpdatedAt,
+	}
+	for i, dbMember := range dbMembers {
+		res.Members[i] = ConversationMember{
+			UserID:   dbMember.UserID,
+			JoinedAt: dbMember.JoinedAt,
+		}
+		if dbMember.LastReadAt.Valid {
+			res.Members[i].LastReadAt = &dbMember.LastReadAt.Time
+		}
+	}
+	writeJSONResponse(w, statusCode, res)
+}
+
+// handlerGetConversations lists the user's conversations, the most recently
+// active first.
+func (cfg *apiConfig) handlerGetConversations(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	dbConversations, err := cfg.db.GetConversationsForUser(r.Context(), database.GetConversationsForUserParams{
+		UserID:    userID,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	res := make([]Conversation, len(dbConversations))
+	for i, dbConversation := range dbConversations {
+		res[i] = Conversation{
+			ID:          dbConversation.ID,
+			MemberIDs:   dbConversation.MemberIds,
+			UnreadCount: dbConversation.UnreadCount,
+			CreatedAt:   dbConversation.CreatedAt,
+			UpdatedAt:   dbConversation.UpdatedAt,
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+// handlerGetConversation returns the conversation with its members and how
+// far each has read, which is what clients show read receipts from.
+func (cfg *apiConfig) handlerGetConversation(w http.ResponseWriter, r *http.Request) {
+	_, conversationID, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+	dbConversation, err := cfg.db.GetConversationByID(r.Context(), conversationID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	cfg.writeConversation(w, r, http.StatusOK, dbConversation)
+}
+
+// handlerGetMessages lists a conversation's messages, newest first. Messages
+// from users the reader blocked, or who blocked them, are left out.
+func (cfg *apiConfig) handlerGetMessages(w http.ResponseWriter, r *http.Request) {
+	userID, conversationID, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	dbMessages, err := cfg.db.GetMessages(r.Context(), database.GetMessagesParams{
+		ConversationID: conversationID,
+		ViewerID:       userID,
+		RowLimit:       limit,
+		RowOffset:      offset,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	res := make([]Message, len(dbMessages))
+	for i, dbMessage := range dbMessages {
+		res[i] = messageResponse(dbMessage)
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+// handlerSendMessage validates the body like a chirp's. Once any member has
+// blocked another, or been blocked, the two can't send to a conversation
+// they share.
+func (cfg *apiConfig) handlerSendMessage(w http.ResponseWriter, r *http.Request) {
+	userID, conversationID, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	params := parameters{}
+	err := DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(params.Body) == "" {
+		writeError(w, http.StatusBadRequest, "Message must not be empty")
+		return
+	}
+	body, err := cleanChirpBody(params.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Message is too long")
+		return
+	}
+	if !cfg.canSendMessages(w, r, userID) {
+		return
+	}
+
+	dbMembers, err := cfg.db.GetConversationMembers(r.Context(), conversationID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	otherIDs := []uuid.UUID{}
+	for _, dbMember := range dbMembers {
+		if dbMember.UserID != userID {
+			otherIDs = append(otherIDs, dbMember.UserID)
+		}
+	}
+	blocks, err := cfg.db.CountBlocksWithUsers(r.Context(), database.CountBlocksWithUsersParams{
+		UserID:   userID,
+		OtherIds: otherIDs,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	if blocks > 0 {
+		writeError(w, http.StatusForbidden, "You can't message some members of this conversation")
+		return
+	}
+
+	now := time.Now().UTC()
+	dbMessage, err := cfg.db.CreateMessage(r.Context(), database.CreateMessageParams{
+		ID:             uuid.New(),
+		ConversationID: conversationID,
+		SenderID:       userID,
+		Body:           body,
+		CreatedAt:      now,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	err = cfg.db.TouchConversation(r.Context(), database.TouchConversationParams{
+		UpdatedAt: now,
+		ID:        conversationID,
+	})
+	if err != nil {
+		logRequestError(r, "Error updating conversation", err)
+	}
+	err = cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		LastReadAt:     sqlNullTime(now),
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		logRequestError(r, "Error marking conversation read", err)
+	}
+	writeJSONResponse(w, http.StatusCreated, messageResponse(dbMessage))
+}
+
+// handlerMarkConversationRead records that the user has read every message
+// in the conversation so far.
+func (cfg *apiConfig) handlerMarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	userID, conversationID, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		LastReadAt:     sqlNullTime(time.Now().UTC()),
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+// conversationMember authenticates the request and checks the caller is a
+// member of the conversationID path value. Conversations of others are
+// reported as not found.
+func (cfg *apiConfig) conversationMember(w http.ResponseWriter, r *http.Request) (userID, conversationID uuid.UUID, ok bool) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return uuid.Nil, uuid.Nil, false
+	}
+	conversationID, err = uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		write404Error(w)
+		return uuid.Nil, uuid.Nil, false
+	}
+	_, err = cfg.db.GetConversationMember(r.Context(), database.GetConversationMemberParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		write404Error(w)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if err != nil {
+		write500Error(w)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, conversationID, true
+}
@@ -0,0 +1,680 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+type ModerationStatus string
+
+const (
+	ModerationStatusVisible ModerationStatus = "visible"
+	// ModerationStatusHidden chirps are only shown to their author.
+	ModerationStatusHidden ModerationStatus = "hidden"
+	// ModerationStatusRemoved chirps are shown to nobody, but kept so an
+	// appeal can bring them back.
+	ModerationStatusRemoved ModerationStatus = "removed"
+)
+
+type ModerationActionType string
+
+const (
+	ModerationActionHide    ModerationActionType = "hide"
+	ModerationActionRemove  ModerationActionType = "remove"
+	ModerationActionRestore ModerationActionType = "restore"
+	ModerationActionWarn    ModerationActionType = "warn"
+	ModerationActionSuspend ModerationActionType = "suspend"
+	ModerationActionDismiss ModerationActionType = "dismiss"
+)
+
+// actionStatuses are the moderation statuses actions on a chirp put it in.
+var actionStatuses = map[ModerationActionType]ModerationStatus{
+	ModerationActionHide:    ModerationStatusHidden,
+	ModerationActionRemove:  ModerationStatusRemoved,
+	ModerationActionRestore: ModerationStatusVisible,
+}
+
+// appealableActions are the actions an appeal can undo.
+var appealableActions = []ModerationActionType{ModerationActionHide, ModerationActionRemove}
+
+type AppealStatus string
+
+const (
+	AppealStatusPending AppealStatus = "pending"
+	AppealStatusGranted AppealStatus = "granted"
+	AppealStatusDenied  AppealStatus = "denied"
+)
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "misinformation", "other"}
+
+const (
+	maxReportDetailsLength  = 1000
+	maxModerationNoteLength = 1000
+	maxAppealMessageLength  = 1000
+)
+
+func (cfg *apiConfig) handlerReportChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticateScope(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	type parameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !slices.Contains(reportReasons, params.Reason) {
+		writeError(w, http.StatusBadRequest, "Reason must be one of spam, harassment, hate, violence, misinformation or other")
+		return
+	}
+	if utf8.RuneCountInString(params.Details) > maxReportDetailsLength {
+		writeError(w, http.StatusBadRequest, "Details must be at most 1000 characters")
+		return
+	}
+
+	dbChirp, err := cfg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	if dbChirp.UserID == userID {
+		writeError(w, http.StatusBadRequest, "You can't report your own chirp")
+		return
+	}
+	dbReport, err := cfg.db.CreateChirpReport(r.Context(), database.CreateChirpReportParams{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now().UTC(),
+		ChirpID:    dbChirp.ID,
+		ReporterID: userID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if isUniqueViolation(err) {
+		writeError(w, http.StatusConflict, "You have already reported this chirp")
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type response struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+		ChirpID   uuid.UUID `json:"chirp_id"`
+		Reason    string    `json:"reason"`
+	}
+	writeJSONResponse(w, http.StatusCreated, response{
+		ID:        dbReport.ID,
+		CreatedAt: dbReport.CreatedAt,
+		ChirpID:   dbReport.ChirpID,
+		Reason:    dbReport.Reason,
+	})
+}
+
+// handlerGetModerationQueue lists chirps with open reports, most reported
+// first.
+func (cfg *apiConfig) handlerGetModerationQueue(w http.ResponseWriter, r *http.Request, actor database.User) {
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	dbItems, err := cfg.db.GetModerationQueue(r.Context(), database.GetModerationQueueParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type queueItem struct {
+		ChirpID          uuid.UUID `json:"chirp_id"`
+		Body             string    `json:"body"`
+		AuthorID         uuid.UUID `json:"author_id"`
+		ModerationStatus string    `json:"moderation_status"`
+		ReportCount      int64     `json:"report_count"`
+		Reasons          []string  `json:"reasons"`
+		FirstReportedAt  time.Time `json:"first_reported_at"`
+		LastReportedAt   time.Time `json:"last_reported_at"`
+	}
+	res := make([]queueItem, len(dbItems))
+	for i, dbItem := range dbItems {
+		res[i] = queueItem{
+			ChirpID:          dbItem.ChirpID,
+			Body:             dbItem.Body,
+			AuthorID:         dbItem.AuthorID,
+			ModerationStatus: dbItem.ModerationStatus,
+			ReportCount:      dbItem.ReportCount,
+			Reasons:          dbItem.Reasons,
+			FirstReportedAt:  dbItem.FirstReportedAt,
+			LastReportedAt:   dbItem.LastReportedAt,
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+type ModerationAction struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	Action    string     `json:"action"`
+	Note      string     `json:"note"`
+}
+
+func moderationActionResponse(dbAction database.ModerationAction) ModerationAction {
+	action := ModerationAction{
+		ID:        dbAction.ID,
+		CreatedAt: dbAction.CreatedAt,
+		Action:    dbAction.Action,
+		Note:      dbAction.Note,
+	}
+	if dbAction.ChirpID.Valid {
+		action.ChirpID = &dbAction.ChirpID.UUID
+	}
+	return action
+}
+
+// handlerGetChirpModeration shows moderators everything about a chirp: its
+// reports, including resolved ones, and the actions taken on it so far.
+func (cfg *apiConfig) handlerGetChirpModeration(w http.ResponseWriter, r *http.Request, actor database.User) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	dbChirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		write404Error(w)
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	dbReports, err := cfg.db.GetReportsByChirpID(r.Context(), chirpID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	dbActions, err := cfg.db.GetModerationActionsByChirpID(r.Context(), uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	chirps, err := cfg.chirpResponses(r.Context(), []database.Chirp{dbChirp})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type report struct {
+		ID         uuid.UUID  `json:"id"`
+		CreatedAt  time.Time  `json:"created_at"`
+		ReporterID uuid.UUID  `json:"reporter_id"`
+		Reason     string     `json:"reason"`
+		Details    string     `json:"details"`
+		ResolvedAt *time.Time `json:"resolved_at"`
+	}
+	type action struct {
+		ModerationAction
+		ModeratorID *uuid.UUID `json:"moderator_id"`
+	}
+	type response struct {
+		Chirp            Chirp    `json:"chirp"`
+		ModerationStatus string   `json:"moderation_status"`
+		Reports          []report `json:"reports"`
+		Actions          []action `json:"actions"`
+	}
+	res := response{
+		Chirp:            chirps[0],
+		ModerationStatus: dbChirp.ModerationStatus,
+		Reports:          make([]report, len(dbReports)),
+		Actions:          make([]action, len(dbActions)),
+	}
+	for i, dbReport := range dbReports {
+		res.Reports[i] = report{
+			ID:         dbReport.ID,
+			CreatedAt:  dbReport.CreatedAt,
+			ReporterID: dbReport.ReporterID,
+			Reason:     dbReport.Reason,
+			Details:    dbReport.Details,
+		}
+		if dbReport.ResolvedAt.Valid {
+			res.Reports[i].ResolvedAt = &dbReport.ResolvedAt.Time
+		}
+	}
+	for i, dbAction := range dbActions {
+		res.Actions[i] = action{ModerationAction: moderationActionResponse(dbAction)}
+		if dbAction.ModeratorID.Valid {
+			res.Actions[i].ModeratorID = &dbAction.ModeratorID.UUID
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+// handlerModerateChirp applies a moderator's decision to a chirp and closes
+// its open reports. Every decision, dismissals included, is recorded with the
+// moderator's note.
+func (cfg *apiConfig) handlerModerateChirp(w http.ResponseWriter, r *http.Request, actor database.User) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	type parameters struct {
+		Action        ModerationActionType `json:"action"`
+		Note          string               `json:"note"`
+		DurationHours int                  `json:"duration_hours"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if utf8.RuneCountInString(params.Note) > maxModerationNoteLength {
+		writeError(w, http.StatusBadRequest, "Note must be at most 1000 characters")
+		return
+	}
+	err = validateSuspensionHours(params.DurationHours)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dbChirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		write404Error(w)
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	author, err := cfg.db.GetUserByID(r.Context(), dbChirp.UserID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	if !Role(actor.Role).outranks(Role(author.Role)) {
+		write403Error(w)
+		return
+	}
+
+	switch params.Action {
+	case ModerationActionHide, ModerationActionRemove, ModerationActionRestore,
+		ModerationActionSuspend, ModerationActionWarn, ModerationActionDismiss:
+	default:
+		writeError(w, http.StatusBadRequest, "Action must be one of hide, remove, restore, warn, suspend or dismiss")
+		return
+	}
+
+	// The decision, its record and the closed reports go in together, so a
+	// failure can't apply an action that was never recorded or leave its
+	// reports open.
+	now := time.Now().UTC()
+	var dbAction database.ModerationAction
+	err = cfg.inTx(r.Context(), func(qtx *database.Queries) error {
+		var err error
+		switch params.Action {
+		case ModerationActionHide, ModerationActionRemove, ModerationActionRestore:
+			_, err = qtx.SetChirpModerationStatus(r.Context(), database.SetChirpModerationStatusParams{
+				ModerationStatus: string(actionStatuses[params.Action]),
+				UpdatedAt:        now,
+				ID:               dbChirp.ID,
+			})
+		case ModerationActionSuspend:
+			_, err = suspendUser(r.Context(), qtx, author.ID, params.Note, params.DurationHours)
+		}
+		if err != nil {
+			return err
+		}
+
+		dbAction, err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ID:           uuid.New(),
+			CreatedAt:    now,
+			ModeratorID:  uuid.NullUUID{UUID: actor.ID, Valid: true},
+			TargetUserID: author.ID,
+			ChirpID:      uuid.NullUUID{UUID: dbChirp.ID, Valid: true},
+			Action:       string(params.Action),
+			Note:         params.Note,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = qtx.ResolveChirpReports(r.Context(), database.ResolveChirpReportsParams{
+			ResolvedAt: sqlNullTime(now),
+			ChirpID:    dbChirp.ID,
+		})
+		return err
+	})
+	if err != nil {
+		logRequestError(r, "Error applying moderation action", err)
+		write500Error(w)
+		return
+	}
+	slog.InfoContext(r.Context(), "Chirp moderated", "chirp_id", dbChirp.ID, "action", params.Action)
+	// Stream clients drop hidden and removed chirps like deleted ones.
+	if (params.Action == ModerationActionHide || params.Action == ModerationActionRemove) &&
+		dbChirp.ModerationStatus == string(ModerationStatusVisible) {
+		cfg.publishChirpEvent(r, ChirpEventDeleted, dbChirp)
+	}
+	cfg.notifyModerationAction(r, author, dbAction)
+	writeJSONResponse(w, http.StatusCreated, moderationActionResponse(dbAction))
+}
+
+// notifyModerationAction emails the author about actions that affect them.
+func (cfg *apiConfig) notifyModerationAction(r *http.Request, author database.User, dbAction database.ModerationAction) {
+	var summary string
+	switch ModerationActionType(dbAction.Action) {
+	case ModerationActionHide:
+		summary = "One of your chirps has been hidden: only you can see it now."
+	case ModerationActionRemove:
+		summary = "One of your chirps has been removed."
+	case ModerationActionWarn:
+		summary = "You have received a warning about one of your chirps."
+	case ModerationActionSuspend:
+		summary = "Your account has been suspended."
+	default:
+		return
+	}
+	body := summary + "\n"
+	if dbAction.Note != "" {
+		body += fmt.Sprintf("\nModerator's note: %s\n", dbAction.Note)
+	}
+	if slices.Contains(appealableActions, ModerationActionType(dbAction.Action)) {
+		body += fmt.Sprintf("\nIf you think this was a mistake, you can appeal from %s/moderation.\n", cfg.baseURL)
+	}
+	cfg.sendMailAsync(r.Context(), mailer.Message{
+		To:      author.Email,
+		Subject: "A moderator reviewed your Chirpy content",
+		Body:    body,
+	})
+}
+
+// handlerGetMyModerationActions lists the actions taken against the user,
+// newest first, along with the state of any appeal.
+func (cfg *apiConfig) handlerGetMyModerationActions(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	dbActions, err := cfg.db.GetModerationActionsByTargetUserID(r.Context(), userID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type action struct {
+		ModerationAction
+		Appealable   bool   `json:"appealable"`
+		AppealStatus string `json:"appeal_status,omitempty"`
+	}
+	res := make([]action, 0, len(dbActions))
+	for _, dbAction := range dbActions {
+		// Dismissed reports aren't something the user needs to hear about.
+		if dbAction.Action == string(ModerationActionDismiss) {
+			continue
+		}
+		item := action{
+			ModerationAction: moderationActionResponse(database.ModerationAction{
+				ID:        dbAction.ID,
+				CreatedAt: dbAction.CreatedAt,
+				ChirpID:   dbAction.ChirpID,
+				Action:    dbAction.Action,
+				Note:      dbAction.Note,
+			}),
+			AppealStatus: dbAction.AppealStatus.String,
+		}
+		item.Appealable = !dbAction.AppealStatus.Valid && dbAction.ChirpID.Valid &&
+			slices.Contains(appealableActions, ModerationActionType(dbAction.Action))
+		res = append(res, item)
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+type ModerationAppeal struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ActionID       uuid.UUID  `json:"action_id"`
+	Message        string     `json:"message"`
+	Status         string     `json:"status"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	ResolutionNote string     `json:"resolution_note"`
+}
+
+func moderationAppealResponse(dbAppeal database.ModerationAppeal) ModerationAppeal {
+	appeal := ModerationAppeal{
+		ID:             dbAppeal.ID,
+		CreatedAt:      dbAppeal.CreatedAt,
+		ActionID:       dbAppeal.ActionID,
+		Message:        dbAppeal.Message,
+		Status:         dbAppeal.Status,
+		ResolutionNote: dbAppeal.ResolutionNote,
+	}
+	if dbAppeal.ResolvedAt.Valid {
+		appeal.ResolvedAt = &dbAppeal.ResolvedAt.Time
+	}
+	return appeal
+}
+
+// handlerAppealModerationAction lets the author contest a hidden or removed
+// chirp, once per action.
+func (cfg *apiConfig) handlerAppealModerationAction(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	actionID, err := uuid.Parse(r.PathValue("actionID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	type parameters struct {
+		Message string `json:"message"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if params.Message == "" || utf8.RuneCountInString(params.Message) > maxAppealMessageLength {
+		writeError(w, http.StatusBadRequest, "Message must be 1-1000 characters")
+		return
+	}
+	dbAction, err := cfg.db.GetModerationActionByID(r.Context(), actionID)
+	if err != nil || dbAction.TargetUserID != userID {
+		write404Error(w)
+		return
+	}
+	if !dbAction.ChirpID.Valid || !slices.Contains(appealableActions, ModerationActionType(dbAction.Action)) {
+		writeError(w, http.StatusBadRequest, "This action can't be appealed")
+		return
+	}
+	dbAppeal, err := cfg.db.CreateModerationAppeal(r.Context(), database.CreateModerationAppealParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		ActionID:  dbAction.ID,
+		UserID:    userID,
+		Message:   params.Message,
+	})
+	if isUniqueViolation(err) {
+		writeError(w, http.StatusConflict, "This action has already been appealed")
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeJSONResponse(w, http.StatusCreated, moderationAppealResponse(dbAppeal))
+}
+
+// handlerGetPendingAppeals lists appeals waiting for a decision, oldest first.
+func (cfg *apiConfig) handlerGetPendingAppeals(w http.ResponseWriter, r *http.Request, actor database.User) {
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	dbAppeals, err := cfg.db.GetPendingModerationAppeals(r.Context(), database.GetPendingModerationAppealsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type appeal struct {
+		ID         uuid.UUID  `json:"id"`
+		CreatedAt  time.Time  `json:"created_at"`
+		UserID     uuid.UUID  `json:"user_id"`
+		Message    string     `json:"message"`
+		ActionID   uuid.UUID  `json:"action_id"`
+		Action     string     `json:"action"`
+		ActionNote string     `json:"action_note"`
+		ChirpID    *uuid.UUID `json:"chirp_id"`
+	}
+	res := make([]appeal, len(dbAppeals))
+	for i, dbAppeal := range dbAppeals {
+		res[i] = appeal{
+			ID:         dbAppeal.ID,
+			CreatedAt:  dbAppeal.CreatedAt,
+			UserID:     dbAppeal.UserID,
+			Message:    dbAppeal.Message,
+			ActionID:   dbAppeal.ActionID,
+			Action:     dbAppeal.Action,
+			ActionNote: dbAppeal.ActionNote,
+		}
+		if dbAppeal.ChirpID.Valid {
+			res[i].ChirpID = &dbAppeal.ChirpID.UUID
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+// handlerResolveAppeal records a moderator's decision on an appeal. Granting
+// it makes the chirp visible again, which is recorded as a restore action.
+func (cfg *apiConfig) handlerResolveAppeal(w http.ResponseWriter, r *http.Request, actor database.User) {
+	appealID, err := uuid.Parse(r.PathValue("appealID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	type parameters struct {
+		Decision AppealStatus `json:"decision"`
+		Note     string       `json:"note"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if params.Decision != AppealStatusGranted && params.Decision != AppealStatusDenied {
+		writeError(w, http.StatusBadRequest, "Decision must be granted or denied")
+		return
+	}
+	if utf8.RuneCountInString(params.Note) > maxModerationNoteLength {
+		writeError(w, http.StatusBadRequest, "Note must be at most 1000 characters")
+		return
+	}
+
+	now := time.Now().UTC()
+	dbAppeal, err := cfg.db.ResolveModerationAppeal(r.Context(), database.ResolveModerationAppealParams{
+		Status:         string(params.Decision),
+		ResolvedAt:     sqlNullTime(now),
+		ResolverID:     uuid.NullUUID{UUID: actor.ID, Valid: true},
+		ResolutionNote: params.Note,
+		ID:             appealID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "No pending appeal found")
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	if params.Decision == AppealStatusGranted {
+		dbAction, err := cfg.db.GetModerationActionByID(r.Context(), dbAppeal.ActionID)
+		if err != nil {
+			write500Error(w)
+			return
+		}
+		if dbAction.ChirpID.Valid {
+			_, err = cfg.db.SetChirpModerationStatus(r.Context(), database.SetChirpModerationStatusParams{
+				ModerationStatus: string(ModerationStatusVisible),
+				UpdatedAt:        now,
+				ID:               dbAction.ChirpID.UUID,
+			})
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				write500Error(w)
+				return
+			}
+			_, err = cfg.db.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+				ID:           uuid.New(),
+				CreatedAt:    now,
+				ModeratorID:  uuid.NullUUID{UUID: actor.ID, Valid: true},
+				TargetUserID: dbAction.TargetUserID,
+				ChirpID:      dbAction.ChirpID,
+				Action:       string(ModerationActionRestore),
+				Note:         "Appeal granted",
+			})
+			if err != nil {
+				write500Error(w)
+				return
+			}
+		}
+	}
+	slog.InfoContext(r.Context(), "Appeal resolved", "appeal_id", dbAppeal.ID, "decision", params.Decision)
+	writeJSONResponse(w, http.StatusOK, moderationAppealResponse(dbAppeal))
+}
+
+// pageParams reads the limit and offset query parameters, writing a 400 if
+// they are out of range.
+func pageParams(w http.ResponseWriter, r *http.Request) (limit, offset int32, ok bool) {
+	l, err := queryInt(r, "limit", defaultAdminPageSize)
+	if err != nil || l < 1 || l > maxAdminPageSize {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+		return 0, 0, false
+	}
+	o, err := queryInt(r, "offset", 0)
+	if err != nil || o < 0 {
+		writeError(w, http.StatusBadRequest, "offset must not be negative")
+		return 0, 0, false
+	}
+	return int32(l), int32(o), true
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/stream"
+	"github.com/google/uuid"
+)
+
+type NotificationType string
+
+// Chirpy has no replies, follows or likes yet; their types get added along
+// with them, so that preferences only list what can actually happen.
+const (
+	NotificationTypeMention NotificationType = "mention"
+)
+
+const notificationsChannel = "notifications"
+
+var notificationTypes = []NotificationType{
+	NotificationTypeMention,
+}
+
+// notificationGroupKey decides which events coalesce into one notification
+// while it is unread: those of the same type about the same chirp, or of the
+// same type with no chirp. A chirp mentions each user once, so mentions
+// never coalesce.
+func notificationGroupKey(notificationType NotificationType, chirpID uuid.NullUUID) string {
+	if !chirpID.Valid {
+		return string(notificationType)
+	}
+	return string(notificationType) + ":" + chirpID.UUID.String()
+}
+
+// notify tells userID that actorID did something, unless it was userID
+// themselves, either has blocked the other, userID muted actorID or turned
+// the type off. Callers log failures rather than fail the request.
+func (cfg *apiConfig) notify(ctx context.Context, userID, actorID uuid.UUID, notificationType NotificationType, chirpID uuid.NullUUID) error {
+	now := time.Now().UTC()
+	notificationID, err := cfg.db.UpsertNotification(ctx, database.UpsertNotificationParams{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Type:      string(notificationType),
+		ChirpID:   chirpID,
+		GroupKey:  notificationGroupKey(notificationType, chirpID),
+		CreatedAt: now,
+		ActorID:   actorID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = cfg.db.AddNotificationActor(ctx, database.AddNotificationActorParams{
+		NotificationID: notificationID,
+		ActorID:        actorID,
+		CreatedAt:      now,
+	})
+	if err != nil {
+		return err
+	}
+
+	event := NotificationEvent{
+		ID:      notificationID,
+		UserID:  userID,
+		Type:    string(notificationType),
+		ActorID: actorID,
+	}
+	if chirpID.Valid {
+		event.ChirpID = &chirpID.UUID
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return cfg.db.NotifyNotification(ctx, string(payload))
+}
+
+// NotificationEvent is pushed to the recipient's live connections whenever a
+// notification is created or someone else joins a coalesced one.
+type NotificationEvent struct {
+	ID      uuid.UUID  `json:"id"`
+	UserID  uuid.UUID  `json:"user_id"`
+	Type    string     `json:"type"`
+	ChirpID *uuid.UUID `json:"chirp_id"`
+	ActorID uuid.UUID  `json:"actor_id"`
+}
+
+// startNotificationStream feeds the hub from notifications made on any
+// replica. Unlike chirp events they aren't replayed: a client that missed
+// some finds them in GET /api/notifications.
+func (cfg *apiConfig) startNotificationStream(ctx context.Context, dbURL string) error {
+	return stream.Listen(ctx, dbURL, notificationsChannel, func(ctx context.Context, payload string) error {
+		if payload == "" {
+			return nil
+		}
+		event := NotificationEvent{}
+		err := json.Unmarshal([]byte(payload), &event)
+		if err != nil {
+			return err
+		}
+		cfg.notificationStream.Publish(stream.Event{
+			Type:   "notification",
+			UserID: event.UserID,
+			Data:   []byte(payload),
+		})
+		return nil
+	})
+}
+
+type Notification struct {
+	ID             uuid.UUID   `json:"id"`
+	Type           string      `json:"type"`
+	ChirpID        *uuid.UUID  `json:"chirp_id"`
+	ActorCount     int64       `json:"actor_count"`
+	RecentActorIDs []uuid.UUID `json:"recent_actor_ids"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	Read           bool        `json:"read"`
+}
+
+// handlerGetNotifications lists the user's notifications, most recently
+// updated first, with unread=true for unread ones only. A coalesced
+// notification moves back to the top whenever someone else joins in.
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	dbNotifications, err := cfg.db.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:     userID,
+		UnreadOnly: r.URL.Query().Get("unread") == "true",
+		RowLimit:   limit,
+		RowOffset:  offset,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	unreadCount, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type response struct {
+		UnreadCount   int64          `json:"unread_count"`
+		Notifications []Notification `json:"notifications"`
+	}
+	res := response{
+		UnreadCount:   unreadCount,
+		Notifications: make([]Notification, len(dbNotifications)),
+	}
+	for i, dbNotification := range dbNotifications {
+		res.Notifications[i] = Notification{
+			ID:             dbNotification.ID,
+			Type:           dbNotification.Type,
+			ActorCount:     dbNotification.ActorCount,
+			RecentActorIDs: dbNotification.RecentActorIds,
+			CreatedAt:      dbNotification.CreatedAt,
+			UpdatedAt:      dbNotification.UpdatedAt,
+			Read:           dbNotification.ReadAt.Valid,
+		}
+		if dbNotification.ChirpID.Valid {
+			res.Notifications[i].ChirpID = &dbNotification.ChirpID.UUID
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+func (cfg *apiConfig) handlerGetUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	unreadCount, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type response struct {
+		UnreadCount int64 `json:"unread_count"`
+	}
+	writeJSONResponse(w, http.StatusOK, response{
+		UnreadCount: unreadCount,
+	})
+}
+
+// handlerMarkNotificationRead is idempotent: marking a notification that is
+// already read succeeds too, and keeps the time it was first read.
+func (cfg *apiConfig) handlerMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	rows, err := cfg.db.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ReadAt: sqlNullTime(time.Now().UTC()),
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	if rows == 0 {
+		write404Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	err = cfg.db.MarkAllNotificationsRead(r.Context(), database.MarkAllNotificationsReadParams{
+		ReadAt: sqlNullTime(time.Now().UTC()),
+		UserID: userID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+// notificationPreferences returns whether each notification type is on.
+// Types the user never changed are on; settings for types that no longer
+// exist are left out.
+func (cfg *apiConfig) notificationPreferences(ctx context.Context, userID uuid.UUID) (map[NotificationType]bool, error) {
+	dbPreferences, err := cfg.db.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	preferences := map[NotificationType]bool{}
+	for _, notificationType := range notificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, dbPreference := range dbPreferences {
+		notificationType := NotificationType(dbPreference.Type)
+		if _, ok := preferences[notificationType]; ok {
+			preferences[notificationType] = dbPreference.Enabled
+		}
+	}
+	return preferences, nil
+}
+
+func (cfg *apiConfig) handlerGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	preferences, err := cfg.notificationPreferences(r.Context(), userID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, preferences)
+}
+
+// handlerUpdateNotificationPreferences takes an object of types to turn on
+// or off, e.g. {"mention": false}. Types left out keep their setting.
+func (cfg *apiConfig) handlerUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	params := map[NotificationType]bool{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	for notificationType := range params {
+		if !slices.Contains(notificationTypes, notificationType) {
+			writeError(w, http.StatusBadRequest, "Unknown notification type: "+string(notificationType))
+			return
+		}
+	}
+	for notificationType, enabled := range params {
+		err = cfg.db.UpsertNotificationPreference(r.Context(), database.UpsertNotificationPreferenceParams{
+			UserID:  userID,
+			Type:    string(notificationType),
+			Enabled: enabled,
+		})
+		if err != nil {
+			write500Error(w)
+			return
+		}
+	}
+	preferences, err := cfg.notificationPreferences(r.Context(), userID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, preferences)
+}
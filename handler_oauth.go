@@ -0,0 +1,599 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	oauthCodeExpirationTime         = 5 * time.Minute
+	oauthAccessTokenExpirationTime  = time.Hour
+	oauthRefreshTokenExpirationTime = 60 * 24 * time.Hour
+)
+
+// oauthError is an error response in the shape RFC 6749 section 5.2 defines.
+type oauthError struct {
+	status      int
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func newOAuthError(status int, code, description string) *oauthError {
+	return &oauthError{status: status, Code: code, Description: description}
+}
+
+func writeOAuthError(w http.ResponseWriter, oauthErr *oauthError) {
+	w.Header().Set("Cache-Control", "no-store")
+	if oauthErr.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+	}
+	writeJSONResponse(w, oauthErr.status, oauthErr)
+}
+
+type authorizationRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// validateAuthorizationRequest returns the client and the scopes asked for.
+// Until the client and redirect URI are known to be good, errors must not be
+// sent back to the redirect URI; the bool reports whether they may be.
+func (cfg *apiConfig) validateAuthorizationRequest(r *http.Request, req authorizationRequest) (database.OauthClient, []string, bool, *oauthError) {
+	clientID, err := uuid.Parse(req.ClientID)
+	if err != nil {
+		return database.OauthClient{}, nil, false, newOAuthError(http.StatusBadRequest, "invalid_request", "Unknown client_id")
+	}
+	dbClient, err := cfg.db.GetOAuthClientByID(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.OauthClient{}, nil, false, newOAuthError(http.StatusBadRequest, "invalid_request", "Unknown client_id")
+	}
+	if err != nil {
+		return database.OauthClient{}, nil, false, newOAuthError(http.StatusInternalServerError, "server_error", "")
+	}
+	if !slices.Contains(dbClient.RedirectUris, req.RedirectURI) {
+		return database.OauthClient{}, nil, false, newOAuthError(http.StatusBadRequest, "invalid_request", "redirect_uri is not registered for this client")
+	}
+
+	if req.ResponseType != "code" {
+		return dbClient, nil, true, newOAuthError(http.StatusBadRequest, "unsupported_response_type", "Only the code response type is supported")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return dbClient, nil, true, newOAuthError(http.StatusBadRequest, "invalid_request", "PKCE with the S256 method is required")
+	}
+	scopes := dbClient.Scopes
+	if req.Scope != "" {
+		scopes, err = auth.ParseScopes(strings.Fields(req.Scope))
+		if err != nil || !isSubset(scopes, dbClient.Scopes) {
+			return dbClient, nil, true, newOAuthError(http.StatusBadRequest, "invalid_scope", "Requested scope is not allowed for this client")
+		}
+	}
+	return dbClient, scopes, true, nil
+}
+
+// authorizationRedirect builds the URL the user agent is sent back to.
+func authorizationRedirect(redirectURI string, values url.Values) string {
+	parsed, _ := url.Parse(redirectURI)
+	query := parsed.Query()
+	for key, value := range values {
+		query[key] = value
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func writeAuthorizationError(w http.ResponseWriter, req authorizationRequest, redirectable bool, oauthErr *oauthError) {
+	if !redirectable {
+		writeOAuthError(w, oauthErr)
+		return
+	}
+	values := url.Values{"error": {oauthErr.Code}}
+	if oauthErr.Description != "" {
+		values.Set("error_description", oauthErr.Description)
+	}
+	if req.State != "" {
+		values.Set("state", req.State)
+	}
+	type response struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+		RedirectTo       string `json:"redirect_to"`
+	}
+	writeJSONResponse(w, oauthErr.status, response{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+		RedirectTo:       authorizationRedirect(req.RedirectURI, values),
+	})
+}
+
+// handlerGetOAuthAuthorization lets the Chirpy app show a consent screen for
+// an authorization request: which client is asking, for what, and whether
+// the user has already agreed to it.
+func (cfg *apiConfig) handlerGetOAuthAuthorization(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	query := r.URL.Query()
+	req := authorizationRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+	dbClient, scopes, redirectable, oauthErr := cfg.validateAuthorizationRequest(r, req)
+	if oauthErr != nil {
+		writeAuthorizationError(w, req, redirectable, oauthErr)
+		return
+	}
+
+	consentRequired := true
+	dbConsent, err := cfg.db.GetOAuthConsent(r.Context(), database.GetOAuthConsentParams{
+		UserID:   userID,
+		ClientID: dbClient.ID,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		write500Error(w)
+		return
+	}
+	if err == nil {
+		consentRequired = !isSubset(scopes, dbConsent.Scopes)
+	}
+
+	type client struct {
+		ID   uuid.UUID `json:"client_id"`
+		Name string    `json:"name"`
+	}
+	type response struct {
+		Client          client   `json:"client"`
+		Scopes          []string `json:"scopes"`
+		ConsentRequired bool     `json:"consent_required"`
+	}
+	writeJSONResponse(w, http.StatusOK, response{
+		Client: client{
+			ID:   dbClient.ID,
+			Name: dbClient.Name,
+		},
+		Scopes:          scopes,
+		ConsentRequired: consentRequired,
+	})
+}
+
+// handlerOAuthAuthorize records the user's decision on an authorization
+// request. On approval the consent is stored and an authorization code bound
+// to the PKCE challenge is issued; either way the response says where to
+// send the user agent.
+func (cfg *apiConfig) handlerOAuthAuthorize(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	type parameters struct {
+		authorizationRequest
+		Approve bool `json:"approve"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req := params.authorizationRequest
+	dbClient, scopes, redirectable, oauthErr := cfg.validateAuthorizationRequest(r, req)
+	if oauthErr != nil {
+		writeAuthorizationError(w, req, redirectable, oauthErr)
+		return
+	}
+	if !params.Approve {
+		writeAuthorizationError(w, req, true, newOAuthError(http.StatusOK, "access_denied", "The user denied the request"))
+		return
+	}
+
+	now := time.Now().UTC()
+	consentScopes := scopes
+	dbConsent, err := cfg.db.GetOAuthConsent(r.Context(), database.GetOAuthConsentParams{
+		UserID:   userID,
+		ClientID: dbClient.ID,
+	})
+	if err == nil {
+		consentScopes = union(dbConsent.Scopes, scopes)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		write500Error(w)
+		return
+	}
+	err = cfg.db.UpsertOAuthConsent(r.Context(), database.UpsertOAuthConsentParams{
+		UserID:    userID,
+		ClientID:  dbClient.ID,
+		Scopes:    consentScopes,
+		CreatedAt: now,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	code, err := auth.MakeOneTimeToken()
+	if err != nil {
+		logRequestError(r, "Error creating authorization code", err)
+		write500Error(w)
+		return
+	}
+	err = cfg.db.CreateOAuthAuthorizationCode(r.Context(), database.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		CreatedAt:     now,
+		ClientID:      dbClient.ID,
+		UserID:        userID,
+		RedirectUri:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     now.Add(oauthCodeExpirationTime),
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	values := url.Values{"code": {code}}
+	if req.State != "" {
+		values.Set("state", req.State)
+	}
+	type response struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+	writeJSONResponse(w, http.StatusOK, response{
+		RedirectTo: authorizationRedirect(req.RedirectURI, values),
+	})
+}
+
+// authenticateOAuthClient identifies the client calling the token,
+// revocation or introspection endpoint, from HTTP Basic credentials or the
+// client_id and client_secret form fields. Public clients only give their ID.
+func (cfg *apiConfig) authenticateOAuthClient(r *http.Request) (database.OauthClient, *oauthError) {
+	clientIDString, secret, ok := r.BasicAuth()
+	if !ok {
+		clientIDString = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+	invalidClient := newOAuthError(http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+	clientID, err := uuid.Parse(clientIDString)
+	if err != nil {
+		return database.OauthClient{}, invalidClient
+	}
+	dbClient, err := cfg.db.GetOAuthClientByID(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.OauthClient{}, invalidClient
+	}
+	if err != nil {
+		return database.OauthClient{}, newOAuthError(http.StatusInternalServerError, "server_error", "")
+	}
+	if dbClient.SecretHash.Valid {
+		secretHash := auth.HashToken(secret)
+		if subtle.ConstantTimeCompare([]byte(secretHash), []byte(dbClient.SecretHash.String)) != 1 {
+			return database.OauthClient{}, invalidClient
+		}
+	} else if secret != "" {
+		return database.OauthClient{}, invalidClient
+	}
+	return dbClient, nil
+}
+
+// handlerOAuthToken is the token endpoint from RFC 6749, supporting the
+// authorization_code grant with PKCE and the refresh_token grant. Refresh
+// tokens are rotated on every use.
+func (cfg *apiConfig) handlerOAuthToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		writeOAuthError(w, newOAuthError(http.StatusBadRequest, "invalid_request", "Malformed form body"))
+		return
+	}
+	dbClient, oauthErr := cfg.authenticateOAuthClient(r)
+	if oauthErr != nil {
+		writeOAuthError(w, oauthErr)
+		return
+	}
+
+	var grant oauthGrant
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		grant, oauthErr = cfg.exchangeAuthorizationCode(r, dbClient)
+	case "refresh_token":
+		grant, oauthErr = cfg.exchangeOAuthRefreshToken(r, dbClient)
+	default:
+		oauthErr = newOAuthError(http.StatusBadRequest, "unsupported_grant_type", "")
+	}
+	if oauthErr != nil {
+		writeOAuthError(w, oauthErr)
+		return
+	}
+	userID, scopes := grant.UserID, grant.Scopes
+
+	accessToken, err := auth.MakeOAuthJWT(userID, dbClient.ID, scopes, cfg.jwtSecret, oauthAccessTokenExpirationTime)
+	if err != nil {
+		logRequestError(r, "Error creating OAuth access token", err)
+		writeOAuthError(w, newOAuthError(http.StatusInternalServerError, "server_error", ""))
+		return
+	}
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		logRequestError(r, "Error creating OAuth refresh token", err)
+		writeOAuthError(w, newOAuthError(http.StatusInternalServerError, "server_error", ""))
+		return
+	}
+	now := time.Now().UTC()
+	_, err = cfg.db.CreateOAuthRefreshToken(r.Context(), database.CreateOAuthRefreshTokenParams{
+		Token:                 refreshToken,
+		CreatedAt:             now,
+		UserID:                userID,
+		ExpiresAt:             now.Add(oauthRefreshTokenExpirationTime),
+		ClientID:              uuid.NullUUID{UUID: dbClient.ID, Valid: true},
+		Scopes:                grant.RefreshScopes,
+		AuthorizationCodeHash: grant.AuthorizationCodeHash,
+	})
+	if err != nil {
+		writeOAuthError(w, newOAuthError(http.StatusInternalServerError, "server_error", ""))
+		return
+	}
+
+	type response struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSONResponse(w, http.StatusOK, response{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    ceilSeconds(oauthAccessTokenExpirationTime),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	})
+}
+
+// oauthGrant is what a token request was granted: Scopes for the new access
+// token and RefreshScopes for the new refresh token. AuthorizationCodeHash
+// is the code the grant started from, which every refresh token rotated from
+// it carries along.
+type oauthGrant struct {
+	UserID                uuid.UUID
+	Scopes                []string
+	RefreshScopes         []string
+	AuthorizationCodeHash sql.NullString
+}
+
+// exchangeAuthorizationCode redeems a code once. A code presented again,
+// most likely by whoever intercepted it, revokes the refresh tokens issued
+// from it, as RFC 6749 section 4.1.2 asks; access tokens issued from it can't
+// be revoked and run out within oauthAccessTokenExpirationTime.
+func (cfg *apiConfig) exchangeAuthorizationCode(r *http.Request, dbClient database.OauthClient) (oauthGrant, *oauthError) {
+	invalidGrant := newOAuthError(http.StatusBadRequest, "invalid_grant", "Invalid authorization code")
+	now := time.Now().UTC()
+	codeHash := auth.HashToken(r.PostForm.Get("code"))
+	dbCode, err := cfg.db.UseOAuthAuthorizationCode(r.Context(), database.UseOAuthAuthorizationCodeParams{
+		UsedAt:   sqlNullTime(now),
+		CodeHash: codeHash,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		err = cfg.db.RevokeRefreshTokensByAuthorizationCode(r.Context(), database.RevokeRefreshTokensByAuthorizationCodeParams{
+			RevokedAt:             sqlNullTime(now),
+			UpdatedAt:             now,
+			AuthorizationCodeHash: sqlNullString(codeHash),
+		})
+		if err != nil {
+			logRequestError(r, "Error revoking tokens of a reused authorization code", err)
+		}
+		return oauthGrant{}, invalidGrant
+	}
+	if err != nil {
+		return oauthGrant{}, newOAuthError(http.StatusInternalServerError, "server_error", "")
+	}
+	if dbCode.ClientID != dbClient.ID || dbCode.RedirectUri != r.PostForm.Get("redirect_uri") {
+		return oauthGrant{}, invalidGrant
+	}
+	if !auth.VerifyPKCE(dbCode.CodeChallenge, r.PostForm.Get("code_verifier")) {
+		return oauthGrant{}, newOAuthError(http.StatusBadRequest, "invalid_grant", "Invalid code_verifier")
+	}
+	return oauthGrant{
+		UserID:                dbCode.UserID,
+		Scopes:                dbCode.Scopes,
+		RefreshScopes:         dbCode.Scopes,
+		AuthorizationCodeHash: sqlNullString(dbCode.CodeHash),
+	}, nil
+}
+
+// exchangeOAuthRefreshToken grants the scopes of the refresh token, which
+// the client may narrow for the new access token but not for the new refresh
+// token.
+func (cfg *apiConfig) exchangeOAuthRefreshToken(r *http.Request, dbClient database.OauthClient) (oauthGrant, *oauthError) {
+	invalidGrant := newOAuthError(http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+	token := r.PostForm.Get("refresh_token")
+	dbToken, err := cfg.db.GetActiveRefreshToken(r.Context(), token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return oauthGrant{}, invalidGrant
+	}
+	if err != nil {
+		return oauthGrant{}, newOAuthError(http.StatusInternalServerError, "server_error", "")
+	}
+	if !dbToken.ClientID.Valid || dbToken.ClientID.UUID != dbClient.ID {
+		return oauthGrant{}, invalidGrant
+	}
+
+	scopes := dbToken.Scopes
+	if requested := r.PostForm.Get("scope"); requested != "" {
+		scopes = strings.Fields(requested)
+		if !isSubset(scopes, dbToken.Scopes) {
+			return oauthGrant{}, newOAuthError(http.StatusBadRequest, "invalid_scope", "Scope exceeds the original grant")
+		}
+	}
+
+	now := time.Now().UTC()
+	revoked, err := cfg.db.RevokeRefreshTokenByToken(r.Context(), database.RevokeRefreshTokenByTokenParams{
+		RevokedAt: sqlNullTime(now),
+		UpdatedAt: now,
+		Token:     token,
+	})
+	if err != nil {
+		return oauthGrant{}, newOAuthError(http.StatusInternalServerError, "server_error", "")
+	}
+	if revoked == 0 {
+		// Another request rotated this token first.
+		return oauthGrant{}, invalidGrant
+	}
+	return oauthGrant{
+		UserID:                dbToken.UserID,
+		Scopes:                scopes,
+		RefreshScopes:         dbToken.Scopes,
+		AuthorizationCodeHash: dbToken.AuthorizationCodeHash,
+	}, nil
+}
+
+// handlerOAuthRevoke implements RFC 7009 for refresh tokens. Access tokens
+// are short-lived JWTs and can't be revoked one by one; revoking the consent
+// cuts them off instead. Unknown tokens are not an error.
+func (cfg *apiConfig) handlerOAuthRevoke(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		writeOAuthError(w, newOAuthError(http.StatusBadRequest, "invalid_request", "Malformed form body"))
+		return
+	}
+	dbClient, oauthErr := cfg.authenticateOAuthClient(r)
+	if oauthErr != nil {
+		writeOAuthError(w, oauthErr)
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, newOAuthError(http.StatusBadRequest, "invalid_request", "token is required"))
+		return
+	}
+
+	if oauthToken, err := auth.ValidateOAuthJWT(token, cfg.jwtSecret); err == nil && oauthToken.ClientID == dbClient.ID {
+		writeOAuthError(w, newOAuthError(http.StatusBadRequest, "unsupported_token_type", "Access tokens can't be revoked; revoke the refresh token instead"))
+		return
+	}
+	dbToken, err := cfg.db.GetActiveRefreshToken(r.Context(), token)
+	if err == nil && dbToken.ClientID.Valid && dbToken.ClientID.UUID == dbClient.ID {
+		now := time.Now().UTC()
+		_, err = cfg.db.RevokeRefreshTokenByToken(r.Context(), database.RevokeRefreshTokenByTokenParams{
+			RevokedAt: sqlNullTime(now),
+			UpdatedAt: now,
+			Token:     token,
+		})
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeOAuthError(w, newOAuthError(http.StatusInternalServerError, "server_error", ""))
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusOK)
+}
+
+// handlerOAuthIntrospect implements RFC 7662. A client can only introspect
+// tokens issued to itself; anything else is reported inactive.
+func (cfg *apiConfig) handlerOAuthIntrospect(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		writeOAuthError(w, newOAuthError(http.StatusBadRequest, "invalid_request", "Malformed form body"))
+		return
+	}
+	dbClient, oauthErr := cfg.authenticateOAuthClient(r)
+	if oauthErr != nil {
+		writeOAuthError(w, oauthErr)
+		return
+	}
+
+	type response struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Subject   string `json:"sub,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		IssuedAt  int64  `json:"iat,omitempty"`
+		ExpiresAt int64  `json:"exp,omitempty"`
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	token := r.PostForm.Get("token")
+
+	if oauthToken, err := auth.ValidateOAuthJWT(token, cfg.jwtSecret); err == nil {
+		if oauthToken.ClientID != dbClient.ID || !cfg.hasOAuthConsent(r, oauthToken) {
+			writeJSONResponse(w, http.StatusOK, response{Active: false})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, response{
+			Active:    true,
+			Scope:     strings.Join(oauthToken.Scopes, " "),
+			ClientID:  dbClient.ID.String(),
+			Subject:   oauthToken.UserID.String(),
+			TokenType: "access_token",
+			IssuedAt:  oauthToken.IssuedAt.Unix(),
+			ExpiresAt: oauthToken.ExpiresAt.Unix(),
+		})
+		return
+	}
+	dbToken, err := cfg.db.GetActiveRefreshToken(r.Context(), token)
+	if err != nil || !dbToken.ClientID.Valid || dbToken.ClientID.UUID != dbClient.ID {
+		writeJSONResponse(w, http.StatusOK, response{Active: false})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, response{
+		Active:    true,
+		Scope:     strings.Join(dbToken.Scopes, " "),
+		ClientID:  dbClient.ID.String(),
+		Subject:   dbToken.UserID.String(),
+		TokenType: "refresh_token",
+		IssuedAt:  dbToken.CreatedAt.Unix(),
+		ExpiresAt: dbToken.ExpiresAt.Unix(),
+	})
+}
+
+// hasOAuthConsent reports whether the user still authorizes the client an
+// access token was issued to, so revoking consent takes effect immediately.
+func (cfg *apiConfig) hasOAuthConsent(r *http.Request, oauthToken auth.OAuthToken) bool {
+	_, err := cfg.db.GetOAuthConsent(r.Context(), database.GetOAuthConsentParams{
+		UserID:   oauthToken.UserID,
+		ClientID: oauthToken.ClientID,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logRequestError(r, "Error checking OAuth consent", err)
+	}
+	return err == nil
+}
+
+func isSubset(subset, set []string) bool {
+	for _, item := range subset {
+		if !slices.Contains(set, item) {
+			return false
+		}
+	}
+	return true
+}
+
+func union(a, b []string) []string {
+	res := slices.Clone(a)
+	for _, item := range b {
+		if !slices.Contains(res, item) {
+			res = append(res, item)
+		}
+	}
+	return res
+}
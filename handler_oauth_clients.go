@@ -0,0 +1,261 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxOAuthClientNameLength = 100
+	maxOAuthRedirectURIs     = 10
+)
+
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+}
+
+func oauthClientResponse(dbClient database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           dbClient.ID,
+		CreatedAt:    dbClient.CreatedAt,
+		Name:         dbClient.Name,
+		RedirectURIs: dbClient.RedirectUris,
+		Scopes:       dbClient.Scopes,
+		Confidential: dbClient.SecretHash.Valid,
+	}
+}
+
+// handlerCreateOAuthClient registers a third-party application. Confidential
+// clients get a secret, returned only here; public clients such as mobile or
+// single-page apps have none and rely on PKCE alone.
+func (cfg *apiConfig) handlerCreateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Scopes       []string `json:"scopes"`
+		Confidential bool     `json:"confidential"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name := strings.TrimSpace(params.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxOAuthClientNameLength {
+		writeError(w, http.StatusBadRequest, "Name must be 1-100 characters")
+		return
+	}
+	if len(params.RedirectURIs) == 0 || len(params.RedirectURIs) > maxOAuthRedirectURIs {
+		writeError(w, http.StatusBadRequest, "Between 1 and 10 redirect URIs are required")
+		return
+	}
+	for _, redirectURI := range params.RedirectURIs {
+		err = validateRedirectURI(redirectURI)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	scopes, err := auth.ParseScopes(params.Scopes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(scopes) == 0 {
+		writeError(w, http.StatusBadRequest, "At least one scope is required")
+		return
+	}
+
+	secret := ""
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		secret, err = auth.MakeOneTimeToken()
+		if err != nil {
+			logRequestError(r, "Error creating client secret", err)
+			write500Error(w)
+			return
+		}
+		secretHash = sqlNullString(auth.HashToken(secret))
+	}
+	dbClient, err := cfg.db.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now().UTC(),
+		OwnerID:      userID,
+		Name:         name,
+		SecretHash:   secretHash,
+		RedirectUris: params.RedirectURIs,
+		Scopes:       scopes,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type response struct {
+		OAuthClient
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+	writeJSONResponse(w, http.StatusCreated, response{
+		OAuthClient:  oauthClientResponse(dbClient),
+		ClientSecret: secret,
+	})
+}
+
+func (cfg *apiConfig) handlerGetOAuthClients(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	dbClients, err := cfg.db.GetOAuthClientsByOwnerID(r.Context(), userID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	res := make([]OAuthClient, len(dbClients))
+	for i, dbClient := range dbClients {
+		res[i] = oauthClientResponse(dbClient)
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+func (cfg *apiConfig) handlerDeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	deleted, err := cfg.db.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{
+		ID:      clientID,
+		OwnerID: userID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	if deleted == 0 {
+		write404Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+// handlerGetOAuthConsents lists the applications the user has authorized.
+func (cfg *apiConfig) handlerGetOAuthConsents(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	dbConsents, err := cfg.db.GetOAuthConsentsByUserID(r.Context(), userID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type consent struct {
+		ClientID   uuid.UUID `json:"client_id"`
+		ClientName string    `json:"client_name"`
+		Scopes     []string  `json:"scopes"`
+		CreatedAt  time.Time `json:"created_at"`
+		UpdatedAt  time.Time `json:"updated_at"`
+	}
+	res := make([]consent, len(dbConsents))
+	for i, dbConsent := range dbConsents {
+		res[i] = consent{
+			ClientID:   dbConsent.ClientID,
+			ClientName: dbConsent.ClientName,
+			Scopes:     dbConsent.Scopes,
+			CreatedAt:  dbConsent.CreatedAt,
+			UpdatedAt:  dbConsent.UpdatedAt,
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+// handlerDeleteOAuthConsent revokes an application's access: its refresh
+// tokens stop working at once and its access tokens are rejected from then on.
+func (cfg *apiConfig) handlerDeleteOAuthConsent(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	deleted, err := cfg.db.DeleteOAuthConsent(r.Context(), database.DeleteOAuthConsentParams{
+		UserID:   userID,
+		ClientID: clientID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	if deleted == 0 {
+		write404Error(w)
+		return
+	}
+	now := time.Now().UTC()
+	err = cfg.db.RevokeOAuthRefreshTokens(r.Context(), database.RevokeOAuthRefreshTokensParams{
+		RevokedAt: sqlNullTime(now),
+		UpdatedAt: now,
+		UserID:    userID,
+		ClientID:  uuid.NullUUID{UUID: clientID, Valid: true},
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+// validateRedirectURI allows https URLs, and plain http only for loopback
+// addresses used by native apps during development.
+func validateRedirectURI(redirectURI string) error {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || !parsed.IsAbs() || parsed.Host == "" {
+		return errors.New("Redirect URIs must be absolute URLs")
+	}
+	if parsed.Fragment != "" {
+		return errors.New("Redirect URIs must not contain a fragment")
+	}
+	if parsed.Scheme == "https" {
+		return nil
+	}
+	host := parsed.Hostname()
+	if parsed.Scheme == "http" && (host == "localhost" || net.ParseIP(host).IsLoopback()) {
+		return nil
+	}
+	return errors.New("Redirect URIs must use https, or http on a loopback address")
+}
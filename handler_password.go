@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/mailer"
+	"github.com/dmytrochumakov/chirpy/internal/passwordpolicy"
+	"github.com/google/uuid"
+)
+
+const passwordResetTokenExpirationTime = time.Hour
+
+func (cfg *apiConfig) handlerForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+	params := parameters{}
+	err := DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		write500Error(w)
+		return
+	}
+
+	// The response is the same whether or not the email belongs to an
+	// account, and it goes out before the account is even looked up, so
+	// timing doesn't give it away either.
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		err := cfg.sendPasswordReset(ctx, params.Email)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error sending password reset", "error", err)
+		}
+	}()
+
+	writeStatusCodeResponse(w, http.StatusAccepted)
+}
+
+// sendPasswordReset emails a reset link to the account with email, if there
+// is one.
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	dbUser, err := cfg.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := auth.MakeOneTimeToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		CreatedAt: time.Now().UTC(),
+		UserID:    dbUser.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetTokenExpirationTime),
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      dbUser.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password for your Chirpy account.\n\n"+
+				"To choose a new password, open:\n%s/reset-password?token=%s\n\n"+
+				"The link expires in one hour. If you didn't ask for this, you can ignore this email.\n",
+			cfg.baseURL, token,
+		),
+	})
+}
+
+func (cfg *apiConfig) handlerResetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	params := parameters{}
+	err := DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		write500Error(w)
+		return
+	}
+
+	// Look the user up without using the token, so a rejected password
+	// doesn't cost the user their reset link.
+	dbUser, err := cfg.db.GetUserFromPasswordResetToken(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	if !cfg.checkPasswordPolicy(w, r, params.Password, dbUser.Email) {
+		return
+	}
+
+	now := time.Now().UTC()
+	userID, err := cfg.db.UsePasswordResetToken(r.Context(), database.UsePasswordResetTokenParams{
+		UsedAt:    sqlNullTime(now),
+		TokenHash: auth.HashToken(params.Token),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(r.Context(), params.Password)
+	if err != nil {
+		logRequestError(r, "Error hashing password", err)
+		write500Error(w)
+		return
+	}
+	err = cfg.db.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		UpdatedAt:      now,
+		ID:             userID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	err = cfg.db.InvalidatePasswordResetTokensByUserID(r.Context(), database.InvalidatePasswordResetTokensByUserIDParams{
+		UsedAt: sqlNullTime(now),
+		UserID: userID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	err = revokeOtherCredentials(r.Context(), cfg.db, userID, "")
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
+
+// revokeOtherCredentials signs userID out everywhere, except for the session
+// of keepRefreshToken, if given: refresh tokens, OAuth ones included,
+// personal access tokens and OAuth consents, without which OAuth access
+// tokens stop working too. db may be in a transaction.
+func revokeOtherCredentials(ctx context.Context, db *database.Queries, userID uuid.UUID, keepRefreshToken string) error {
+	now := time.Now().UTC()
+	err := db.RevokeOtherRefreshTokens(ctx, database.RevokeOtherRefreshTokensParams{
+		RevokedAt: sqlNullTime(now),
+		UpdatedAt: now,
+		UserID:    userID,
+		Token:     keepRefreshToken,
+	})
+	if err != nil {
+		return err
+	}
+	err = db.RevokePersonalAccessTokensByUserID(ctx, database.RevokePersonalAccessTokensByUserIDParams{
+		RevokedAt: sqlNullTime(now),
+		UserID:    userID,
+	})
+	if err != nil {
+		return err
+	}
+	return db.DeleteOAuthConsentsByUserID(ctx, userID)
+}
+
+// passwordPolicyFromEnv builds the password policy for new hashes written by
+// hasher. bcrypt ignores everything past its first 72 bytes, so with bcrypt
+// longer passwords are refused rather than silently cut short.
+func passwordPolicyFromEnv(hasher auth.PasswordHasher) (*passwordpolicy.Policy, error) {
+	policy, err := passwordpolicy.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := hasher.(*auth.BcryptHasher); ok {
+		policy.MaxBytes = auth.BcryptMaxPasswordBytes
+	}
+	return policy, nil
+}
+
+// checkPasswordPolicy answers 400 with the list of violations if password
+// isn't acceptable for the account with the given email, and reports whether
+// the handler may go on.
+func (cfg *apiConfig) checkPasswordPolicy(w http.ResponseWriter, r *http.Request, password, email string) bool {
+	err := cfg.passwordPolicy.Check(r.Context(), password, email)
+	if err == nil {
+		return true
+	}
+	var validationErr *passwordpolicy.ValidationError
+	if !errors.As(err, &validationErr) {
+		logRequestError(r, "Error checking password policy", err)
+		write500Error(w)
+		return false
+	}
+
+	type response struct {
+		Error      string                     `json:"error"`
+		Violations []passwordpolicy.Violation `json:"violations"`
+	}
+	writeJSONResponse(w, http.StatusBadRequest, response{
+		Error:      "Password does not meet the requirements",
+		Violations: validationErr.Violations,
+	})
+	return false
+}
+
+// sendMailAsync sends msg without holding up the response. The request's
+// values (request ID, trace) are kept but not its cancellation.
+func (cfg *apiConfig) sendMailAsync(ctx context.Context, msg mailer.Message) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		err := cfg.mailer.Send(ctx, msg)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error sending email", "subject", msg.Subject, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/passwordpolicy"
+)
+
+func TestPasswordPolicyFromEnv(t *testing.T) {
+	for _, key := range []string{"PASSWORD_MIN_LENGTH", "PASSWORD_MAX_LENGTH", "BREACHED_PASSWORDS_DIR"} {
+		t.Setenv(key, "")
+	}
+	// 40 characters, 80 bytes: within the length limit, past bcrypt's.
+	long := strings.Repeat("é", 40)
+
+	tests := []struct {
+		name         string
+		hasher       auth.PasswordHasher
+		wantMaxBytes int
+		wantLongOK   bool
+	}{
+		{name: "argon2id", hasher: auth.NewArgon2idHasher(auth.DefaultArgon2idParams), wantMaxBytes: 0, wantLongOK: true},
+		{name: "bcrypt", hasher: auth.NewBcryptHasher(auth.DefaultBcryptCost), wantMaxBytes: auth.BcryptMaxPasswordBytes, wantLongOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := passwordPolicyFromEnv(tt.hasher)
+			if err != nil {
+				t.Fatalf("passwordPolicyFromEnv() error = %v", err)
+			}
+			if policy.MaxBytes != tt.wantMaxBytes {
+				t.Errorf("MaxBytes = %d, want %d", policy.MaxBytes, tt.wantMaxBytes)
+			}
+			err = policy.Check(context.Background(), long, "")
+			if ok := err == nil; ok != tt.wantLongOK {
+				t.Errorf("Check(%d bytes) error = %v, want ok %v", len(long), err, tt.wantLongOK)
+			}
+			var validationErr *passwordpolicy.ValidationError
+			if err != nil && !errors.As(err, &validationErr) {
+				t.Errorf("Check() error = %v, want a *ValidationError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxLocationLength    = 30
+	maxAvatarURLLength   = 2048
+)
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+// Profile is the public view of a user. It must never include the email
+// address or anything else only the user themselves should see.
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	Location    string    `json:"location"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+func profileResponse(dbUser database.User) Profile {
+	return Profile{
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		Handle:      dbUser.Handle.String,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
+		AvatarURL:   dbUser.AvatarUrl,
+		Location:    dbUser.Location,
+		IsChirpyRed: dbUser.IsChirpyRed,
+	}
+}
+
+func (cfg *apiConfig) handlerGetUserProfile(w http.ResponseWriter, r *http.Request) {
+	dbUser, err := cfg.db.GetUserByHandle(r.Context(), normalizeHandle(r.PathValue("handle")))
+	if errors.Is(err, sql.ErrNoRows) {
+		write404Error(w)
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, profileResponse(dbUser))
+}
+
+// resolveUserID accepts either a user's UUID or their handle, with or
+// without a leading @.
+func (cfg *apiConfig) resolveUserID(ctx context.Context, idOrHandle string) (uuid.UUID, error) {
+	if userID, err := uuid.Parse(idOrHandle); err == nil {
+		return userID, nil
+	}
+	dbUser, err := cfg.db.GetUserByHandle(ctx, normalizeHandle(idOrHandle))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return dbUser.ID, nil
+}
+
+func normalizeHandle(handle string) string {
+	return strings.TrimPrefix(strings.TrimSpace(handle), "@")
+}
+
+func validateHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return errors.New("Handle must be 3-30 letters, digits or underscores")
+	}
+	return nil
+}
+
+func validateProfileText(field, value string, maxLength int) error {
+	if utf8.RuneCountInString(value) > maxLength {
+		return errors.New(field + " is too long")
+	}
+	return nil
+}
+
+func validateAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+	if len(avatarURL) > maxAvatarURLLength {
+		return errors.New("Avatar URL is too long")
+	}
+	parsed, err := url.Parse(avatarURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return errors.New("Avatar URL must be an http or https URL")
+	}
+	return nil
+}
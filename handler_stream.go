@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/stream"
+	"github.com/google/uuid"
+)
+
+type ChirpEventType string
+
+const (
+	ChirpEventCreated ChirpEventType = "chirp_created"
+	ChirpEventDeleted ChirpEventType = "chirp_deleted"
+)
+
+const (
+	chirpEventsChannel     = "chirp_events"
+	chirpEventBatchSize    = 500
+	chirpEventRetention    = 24 * time.Hour
+	chirpEventCommitGrace  = 10 * time.Second
+	streamBufferSize       = 64
+	streamHeartbeatPeriod  = 15 * time.Second
+	streamReconnectDelayMs = 3000
+)
+
+// publishChirpEvent appends to the chirp event log and wakes up the stream
+// hub of every replica. The chirp change has already happened, so failures
+// are only logged: live clients miss the event until they reconnect.
+func (cfg *apiConfig) publishChirpEvent(r *http.Request, eventType ChirpEventType, dbChirp database.Chirp) {
+	_, err := cfg.db.CreateChirpEvent(r.Context(), database.CreateChirpEventParams{
+		Type:      string(eventType),
+		ChirpID:   dbChirp.ID,
+		UserID:    dbChirp.UserID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		logRequestError(r, "Error recording chirp event", err)
+		return
+	}
+	err = cfg.db.NotifyChirpEvents(r.Context())
+	if err != nil {
+		logRequestError(r, "Error notifying chirp event", err)
+	}
+}
+
+// startChirpStream feeds the hub from the event log, starting after the
+// latest event so that a restart doesn't replay history to nobody.
+func (cfg *apiConfig) startChirpStream(ctx context.Context, dbURL string) error {
+	latestID, err := cfg.db.GetLatestChirpEventID(ctx)
+	if err != nil {
+		return err
+	}
+	cursor := newChirpEventCursor(latestID)
+	return stream.Listen(ctx, dbURL, chirpEventsChannel, func(ctx context.Context, _ string) error {
+		return cfg.readChirpEvents(ctx, cursor, cfg.chirpStream.Publish)
+	})
+}
+
+// chirpEventCursor tracks which events of the log have been read. IDs are
+// handed out before commit, so an event can become visible after one with a
+// higher ID: the cursor only moves past events older than
+// chirpEventCommitGrace, which every earlier ID is assumed to have committed
+// within, and remembers the younger ones it has read so that re-reading them
+// doesn't repeat them.
+type chirpEventCursor struct {
+	settled int64
+	seen    map[int64]bool
+}
+
+func newChirpEventCursor(afterID int64) *chirpEventCursor {
+	return &chirpEventCursor{settled: afterID, seen: map[int64]bool{}}
+}
+
+// has reports whether the event with the given ID has been read.
+func (c *chirpEventCursor) has(id int64) bool {
+	return id <= c.settled || c.seen[id]
+}
+
+// readChirpEvents passes every event the cursor hasn't read yet to fn,
+// oldest first, and moves the cursor past them. Created events of chirps
+// that are gone or no longer visible are skipped.
+func (cfg *apiConfig) readChirpEvents(ctx context.Context, cursor *chirpEventCursor, fn func(stream.Event)) error {
+	settledBefore := time.Now().UTC().Add(-chirpEventCommitGrace)
+	settling := true
+	afterID := cursor.settled
+	for {
+		dbEvents, err := cfg.db.GetChirpEventsAfter(ctx, database.GetChirpEventsAfterParams{
+			AfterID:  afterID,
+			RowLimit: chirpEventBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		var createdIDs []uuid.UUID
+		for _, dbEvent := range dbEvents {
+			if ChirpEventType(dbEvent.Type) == ChirpEventCreated && dbEvent.Body.Valid && !cursor.has(dbEvent.ID) {
+				createdIDs = append(createdIDs, dbEvent.ChirpID)
+			}
+		}
+		attachments, err := cfg.chirpAttachments(ctx, createdIDs)
+		if err != nil {
+			return err
+		}
+		for _, dbEvent := range dbEvents {
+			afterID = dbEvent.ID
+			if !cursor.has(dbEvent.ID) {
+				event, ok, err := chirpStreamEvent(dbEvent, attachments[dbEvent.ChirpID])
+				if err != nil {
+					return err
+				}
+				if ok {
+					fn(event)
+				}
+				cursor.seen[dbEvent.ID] = true
+			}
+			if settling && dbEvent.CreatedAt.Before(settledBefore) {
+				cursor.settled = dbEvent.ID
+				delete(cursor.seen, dbEvent.ID)
+			} else {
+				settling = false
+			}
+		}
+		if len(dbEvents) < chirpEventBatchSize {
+			return nil
+		}
+	}
+}
+
+func chirpStreamEvent(dbEvent database.GetChirpEventsAfterRow, attachments []Attachment) (stream.Event, bool, error) {
+	var payload any
+	switch ChirpEventType(dbEvent.Type) {
+	case ChirpEventCreated:
+		if !dbEvent.Body.Valid {
+			return stream.Event{}, false, nil
+		}
+		chirp := chirpResponse(database.Chirp{
+			ID:               dbEvent.ChirpID,
+			CreatedAt:        dbEvent.ChirpCreatedAt.Time,
+			UpdatedAt:        dbEvent.ChirpUpdatedAt.Time,
+			Body:             dbEvent.Body.String,
+			UserID:           dbEvent.UserID,
+			ModerationStatus: string(ModerationStatusVisible),
+		})
+		if attachments != nil {
+			chirp.Attachments = attachments
+		}
+		payload = chirp
+	case ChirpEventDeleted:
+		type deletedChirp struct {
+			ID     uuid.UUID `json:"id"`
+			UserID uuid.UUID `json:"user_id"`
+		}
+		payload = deletedChirp{
+			ID:     dbEvent.ChirpID,
+			UserID: dbEvent.UserID,
+		}
+	default:
+		return stream.Event{}, false, nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return stream.Event{}, false, err
+	}
+	return stream.Event{
+		ID:     dbEvent.ID,
+		Type:   dbEvent.Type,
+		UserID: dbEvent.UserID,
+		Data:   data,
+	}, true, nil
+}
+
+// streamHiddenAuthors returns the users whose chirps the viewer must not be
+// streamed, matching the chirp listings: blocks either way, and mutes unless
+// the viewer asked for one author. Blocks and mutes made after connecting
+// only apply once the client reconnects.
+func (cfg *apiConfig) streamHiddenAuthors(ctx context.Context, viewerID uuid.NullUUID, filteredByAuthor bool) (map[uuid.UUID]bool, error) {
+	hidden := map[uuid.UUID]bool{}
+	if !viewerID.Valid {
+		return hidden, nil
+	}
+	blocked, err := cfg.db.GetBlockedOrBlockingUserIDs(ctx, viewerID.UUID)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range blocked {
+		hidden[userID] = true
+	}
+	if filteredByAuthor {
+		return hidden, nil
+	}
+	muted, err := cfg.db.GetMutedUserIDs(ctx, viewerID.UUID)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range muted {
+		hidden[userID] = true
+	}
+	return hidden, nil
+}
+
+// handlerStream pushes chirp_created and chirp_deleted events as Server-Sent
+// Events, optionally only for author_id (a UUID or handle). Clients that
+// reconnect with Last-Event-ID get what they missed first, as long as it is
+// within chirpEventRetention. A client too slow to keep up is disconnected
+// and can resume the same way.
+func (cfg *apiConfig) handlerStream(w http.ResponseWriter, r *http.Request) {
+	viewerID := cfg.chirpViewer(r)
+	var authorID uuid.NullUUID
+	if author := r.URL.Query().Get("author_id"); author != "" {
+		userID, err := cfg.resolveUserID(r.Context(), author)
+		if errors.Is(err, sql.ErrNoRows) {
+			write404Error(w)
+			return
+		}
+		if err != nil {
+			write500Error(w)
+			return
+		}
+		authorID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+	var lastEventID int64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || id < 0 {
+			writeError(w, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		lastEventID = id
+	}
+	hidden, err := cfg.streamHiddenAuthors(r.Context(), viewerID, authorID.Valid)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	wanted := func(event stream.Event) bool {
+		if authorID.Valid && event.UserID != authorID.UUID {
+			return false
+		}
+		return !hidden[event.UserID]
+	}
+
+	// Subscribe before replaying, so nothing published in between is lost;
+	// live events already replayed are skipped by ID.
+	sub := cfg.chirpStream.Subscribe(streamBufferSize)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_, err = fmt.Fprintf(w, "retry: %d\n\n", streamReconnectDelayMs)
+	if err == nil {
+		err = rc.Flush()
+	}
+	if err != nil {
+		return
+	}
+
+	send := func(event stream.Event) error {
+		if !wanted(event) {
+			return nil
+		}
+		_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+		if err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	cursor := newChirpEventCursor(lastEventID)
+	if lastEventID > 0 {
+		var sendErr error
+		err = cfg.readChirpEvents(r.Context(), cursor, func(event stream.Event) {
+			if sendErr == nil {
+				sendErr = send(event)
+			}
+		})
+		if err != nil {
+			logRequestError(r, "Error replaying chirp events", err)
+			return
+		}
+		if sendErr != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatPeriod)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if cursor.has(event.ID) {
+				continue
+			}
+			err = send(event)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+			if err == nil {
+				err = rc.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxTokenNameLength = 100
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+func personalAccessTokenResponse(dbToken database.PersonalAccessToken) PersonalAccessToken {
+	token := PersonalAccessToken{
+		ID:        dbToken.ID,
+		CreatedAt: dbToken.CreatedAt,
+		Name:      dbToken.Name,
+		Scopes:    dbToken.Scopes,
+	}
+	if dbToken.LastUsedAt.Valid {
+		token.LastUsedAt = &dbToken.LastUsedAt.Time
+	}
+	if dbToken.ExpiresAt.Valid {
+		token.ExpiresAt = &dbToken.ExpiresAt.Time
+	}
+	return token
+}
+
+// handlerCreatePersonalAccessToken issues a token for scripts and bots. The
+// token itself is only ever returned here; just its hash is stored. Managing
+// tokens needs a login session, so a token can't be used to mint another.
+func (cfg *apiConfig) handlerCreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+
+	type parameters struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name := strings.TrimSpace(params.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxTokenNameLength {
+		writeError(w, http.StatusBadRequest, "Name must be 1-100 characters")
+		return
+	}
+	scopes, err := auth.ParseScopes(params.Scopes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(scopes) == 0 {
+		writeError(w, http.StatusBadRequest, "At least one scope is required")
+		return
+	}
+	if params.ExpiresInDays < 0 {
+		writeError(w, http.StatusBadRequest, "expires_in_days must not be negative")
+		return
+	}
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		logRequestError(r, "Error creating personal access token", err)
+		write500Error(w)
+		return
+	}
+	now := time.Now().UTC()
+	expiresAt := sql.NullTime{}
+	if params.ExpiresInDays > 0 {
+		expiresAt = sqlNullTime(now.AddDate(0, 0, params.ExpiresInDays))
+	}
+	dbToken, err := cfg.db.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UserID:    userID,
+		Name:      name,
+		TokenHash: auth.HashToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type response struct {
+		PersonalAccessToken
+		Token string `json:"token"`
+	}
+	writeJSONResponse(w, http.StatusCreated, response{
+		PersonalAccessToken: personalAccessTokenResponse(dbToken),
+		Token:               token,
+	})
+}
+
+func (cfg *apiConfig) handlerGetPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	dbTokens, err := cfg.db.GetPersonalAccessTokensByUserID(r.Context(), userID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	res := make([]PersonalAccessToken, len(dbTokens))
+	for i, dbToken := range dbTokens {
+		res[i] = personalAccessTokenResponse(dbToken)
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+func (cfg *apiConfig) handlerRevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	revoked, err := cfg.db.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+		RevokedAt: sqlNullTime(time.Now().UTC()),
+		ID:        tokenID,
+		UserID:    userID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	if revoked == 0 {
+		write404Error(w)
+		return
+	}
+	writeStatusCodeResponse(w, http.StatusNoContent)
+}
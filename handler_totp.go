@@ -0,0 +1,204 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	if dbUser.TotpEnabled {
+		writeError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		logRequestError(r, "Error creating TOTP secret", err)
+		write500Error(w)
+		return
+	}
+	err = cfg.db.SetUserTOTPSecret(r.Context(), database.SetUserTOTPSecretParams{
+		TotpSecret: sqlNullString(secret),
+		UpdatedAt:  time.Now().UTC(),
+		ID:         dbUser.ID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type response struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+	writeJSONResponse(w, http.StatusOK, response{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(dbUser.Email, secret),
+	})
+}
+
+func (cfg *apiConfig) handlerConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		write500Error(w)
+		return
+	}
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	if dbUser.TotpEnabled {
+		writeError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if !dbUser.TotpSecret.Valid {
+		writeError(w, http.StatusBadRequest, "Two-factor enrollment has not been started")
+		return
+	}
+
+	step, err := auth.ValidateTOTP(dbUser.TotpSecret.String, params.Code, time.Now())
+	if err != nil {
+		logRequestWarn(r, "Invalid TOTP confirmation code", err)
+		writeError(w, http.StatusUnauthorized, "Invalid two-factor code")
+		return
+	}
+	err = cfg.db.EnableUserTOTP(r.Context(), database.EnableUserTOTPParams{
+		TotpLastUsedStep: step,
+		UpdatedAt:        time.Now().UTC(),
+		ID:               dbUser.ID,
+	})
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	cfg.writeNewRecoveryCodes(w, r, dbUser.ID)
+}
+
+func (cfg *apiConfig) handlerRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		write401Error(w)
+		return
+	}
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		write500Error(w)
+		return
+	}
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		write404Error(w)
+		return
+	}
+	if !dbUser.TotpEnabled {
+		writeError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+	err = cfg.verifyTOTPCode(r, dbUser, params.Code)
+	if err != nil {
+		logRequestWarn(r, "Invalid TOTP code", err)
+		writeError(w, http.StatusUnauthorized, "Invalid two-factor code")
+		return
+	}
+	cfg.writeNewRecoveryCodes(w, r, dbUser.ID)
+}
+
+// writeNewRecoveryCodes replaces the user's recovery codes. The plaintext codes
+// are only ever shown in this response.
+func (cfg *apiConfig) writeNewRecoveryCodes(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	codes, err := auth.MakeRecoveryCodes()
+	if err != nil {
+		logRequestError(r, "Error creating recovery codes", err)
+		write500Error(w)
+		return
+	}
+	err = cfg.db.DeleteTOTPRecoveryCodesByUserID(r.Context(), userID)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+	for _, code := range codes {
+		err = cfg.db.CreateTOTPRecoveryCode(r.Context(), database.CreateTOTPRecoveryCodeParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UserID:    userID,
+			CodeHash:  auth.HashRecoveryCode(code),
+		})
+		if err != nil {
+			write500Error(w)
+			return
+		}
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	writeJSONResponse(w, http.StatusOK, response{
+		RecoveryCodes: codes,
+	})
+}
+
+// verifyTOTPCode checks a code for a user with TOTP enabled and records its
+// time step, so each code is accepted at most once.
+func (cfg *apiConfig) verifyTOTPCode(r *http.Request, dbUser database.User, code string) error {
+	step, err := auth.ValidateTOTP(dbUser.TotpSecret.String, code, time.Now())
+	if err != nil {
+		return auth.ErrInvalidTOTPCode
+	}
+	updated, err := cfg.db.UpdateUserTOTPLastUsedStep(r.Context(), database.UpdateUserTOTPLastUsedStepParams{
+		TotpLastUsedStep: step,
+		ID:               dbUser.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return auth.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+func (cfg *apiConfig) useRecoveryCode(r *http.Request, dbUser database.User, code string) error {
+	updated, err := cfg.db.UseTOTPRecoveryCode(r.Context(), database.UseTOTPRecoveryCodeParams{
+		UsedAt:   sqlNullTime(time.Now().UTC()),
+		UserID:   dbUser.ID,
+		CodeHash: auth.HashRecoveryCode(code),
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return auth.ErrInvalidTOTPCode
+	}
+	return nil
+}
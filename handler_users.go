@@ -1,38 +1,104 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/dmytrochumakov/chirpy/internal/auth"
 	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/mailer"
 	"github.com/google/uuid"
 )
 
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Handle        string    `json:"handle"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+	AvatarURL     string    `json:"avatar_url"`
+	Location      string    `json:"location"`
+	Role          Role      `json:"role"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+func userResponse(dbUser database.User) User {
+	user := User{
+		ID:            dbUser.ID,
+		CreatedAt:     dbUser.CreatedAt,
+		UpdatedAt:     dbUser.UpdatedAt,
+		Email:         dbUser.Email,
+		EmailVerified: dbUser.EmailVerified,
+		IsChirpyRed:   dbUser.IsChirpyRed,
+		Handle:        dbUser.Handle.String,
+		DisplayName:   dbUser.DisplayName,
+		Bio:           dbUser.Bio,
+		AvatarURL:     dbUser.AvatarUrl,
+		Location:      dbUser.Location,
+		Role:          Role(dbUser.Role),
+	}
+	if dbUser.DeletionScheduledAt.Valid {
+		user.DeletionScheduledAt = &dbUser.DeletionScheduledAt.Time
+	}
+	return user
+}
+
+// normalizeEmail accepts a bare address, without a display name or angle
+// brackets, and lowercases it so that one mailbox can't hold two accounts.
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", errors.New("Invalid email address")
+	}
+	return strings.ToLower(email), nil
 }
 
 func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request) {
 	type requestParams struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Handle   string `json:"handle"`
 	}
 	reqParans := requestParams{}
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&reqParans)
 	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
 		write500Error(w)
 		return
 	}
-	hashedPassword, err := auth.HashPassword(reqParans.Password)
+	reqParans.Email, err = normalizeEmail(reqParans.Email)
 	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	handle := sql.NullString{}
+	if reqParans.Handle != "" {
+		reqParans.Handle = normalizeHandle(reqParans.Handle)
+		err = validateHandle(reqParans.Handle)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		handle = sqlNullString(reqParans.Handle)
+	}
+	if !cfg.checkPasswordPolicy(w, r, reqParans.Password, reqParans.Email) {
+		return
+	}
+	hashedPassword, err := auth.HashPassword(r.Context(), reqParans.Password)
+	if err != nil {
+		logRequestError(r, "Error hashing password", err)
 		write500Error(w)
 		return
 	}
@@ -42,25 +108,27 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		UpdatedAt:      time.Now().UTC(),
 		Email:          reqParans.Email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	})
+	if isUniqueViolation(err) {
+		writeError(w, http.StatusConflict, userConflictMessage(err))
+		return
+	}
 	if err != nil {
 		write500Error(w)
 		return
 	}
 
-	respUser := User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+	err = cfg.sendEmailVerification(r, dbUser.ID, dbUser.Email)
+	if err != nil {
+		logRequestError(r, "Error sending email verification", err)
 	}
 
-	dat, err := json.Marshal(respUser)
+	dat, err := json.Marshal(userResponse(dbUser))
 
 	w.WriteHeader(201)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
+		logRequestError(r, "Error marshalling JSON", err)
 		write500Error(w)
 		return
 	}
@@ -69,38 +137,176 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 
 }
 
-func (cfg *apiConfig) handlerUpdateUserEmailAndPassword(w http.ResponseWriter, r *http.Request) {
-	authToken, err := auth.GetBearerToken(r.Header)
+// handlerUpdateUser applies a partial update to the authenticated user's
+// profile: only fields present in the body are changed.
+func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticateScope(r, auth.ScopeProfileWrite)
 	if err != nil {
-		write401Error(w)
+		writeAuthError(w, err)
+		return
+	}
+
+	type parameters struct {
+		Email       *string `json:"email"`
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+		Location    *string `json:"location"`
+	}
+	params := parameters{}
+	err = DecodeJSON(r, &params)
+	if err != nil {
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	userID, err := auth.ValidateJWT(authToken, cfg.jwtSecret)
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		write404Error(w)
+		return
+	}
+
+	profile := database.UpdateUserProfileParams{
+		Handle:      dbUser.Handle,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
+		AvatarUrl:   dbUser.AvatarUrl,
+		Location:    dbUser.Location,
+		UpdatedAt:   time.Now().UTC(),
+		ID:          dbUser.ID,
+	}
+	if params.Handle != nil {
+		handle := normalizeHandle(*params.Handle)
+		err = validateHandle(handle)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		profile.Handle = sqlNullString(handle)
+	}
+	if params.DisplayName != nil {
+		profile.DisplayName = strings.TrimSpace(*params.DisplayName)
+	}
+	if params.Bio != nil {
+		profile.Bio = strings.TrimSpace(*params.Bio)
+	}
+	if params.AvatarURL != nil {
+		profile.AvatarUrl = strings.TrimSpace(*params.AvatarURL)
+	}
+	if params.Location != nil {
+		profile.Location = strings.TrimSpace(*params.Location)
+	}
+	for _, err := range []error{
+		validateProfileText("Display name", profile.DisplayName, maxDisplayNameLength),
+		validateProfileText("Bio", profile.Bio, maxBioLength),
+		validateProfileText("Location", profile.Location, maxLocationLength),
+		validateAvatarURL(profile.AvatarUrl),
+	} {
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if params.Email != nil {
+		email, err := normalizeEmail(*params.Email)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		params.Email = &email
+	}
+	changingEmail := params.Email != nil && !strings.EqualFold(*params.Email, dbUser.Email)
+	if changingEmail {
+		// Whoever controls the email controls the account, so a personal
+		// access token isn't enough to change it.
+		if _, err := cfg.authenticate(r); err != nil {
+			writeError(w, http.StatusForbidden, "Changing email requires signing in with a password")
+			return
+		}
+		_, err = cfg.db.GetUserByEmail(r.Context(), *params.Email)
+		if err == nil {
+			writeError(w, http.StatusConflict, "Email is already in use")
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			write500Error(w)
+			return
+		}
+	}
+
+	dbUser, err = cfg.db.UpdateUserProfile(r.Context(), profile)
+	if isUniqueViolation(err) {
+		writeError(w, http.StatusConflict, userConflictMessage(err))
+		return
+	}
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	type response struct {
+		User
+		PendingEmail string `json:"pending_email,omitempty"`
+	}
+	resp := response{User: userResponse(dbUser)}
+	if changingEmail {
+		err = cfg.requestEmailChange(r, dbUser, *params.Email)
+		if err != nil {
+			logRequestError(r, "Error requesting email change", err)
+			write500Error(w)
+			return
+		}
+		resp.PendingEmail = *params.Email
+	}
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
+// handlerChangePassword replaces the password after checking the current one,
+// then signs out every other session the way a reset does. The caller keeps
+// the session of the refresh_token they send, with a new access token, or
+// gets a fresh pair if they send none.
+func (cfg *apiConfig) handlerChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
 	if err != nil {
 		write401Error(w)
 		return
 	}
 
 	type parameters struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+		RefreshToken    string `json:"refresh_token"`
 	}
 	params := parameters{}
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&params)
+	err = DecodeJSON(r, &params)
 	if err != nil {
-		write500Error(w)
+		logRequestWarn(r, "Error decoding parameters", err)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	hashedPassword, err := auth.HashPassword(params.Password)
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
 	if err != nil {
+		write404Error(w)
+		return
+	}
+	if !cfg.checkCurrentPassword(w, r, dbUser, params.CurrentPassword, "Current password is incorrect") {
+		return
+	}
+
+	if !cfg.checkPasswordPolicy(w, r, params.NewPassword, dbUser.Email) {
+		return
+	}
+	hashedPassword, err := auth.HashPassword(r.Context(), params.NewPassword)
+	if err != nil {
+		logRequestError(r, "Error hashing password", err)
 		write500Error(w)
 		return
 	}
-	dbUser, err := cfg.db.UpdateUserEmailAndPassword(r.Context(), database.UpdateUserEmailAndPasswordParams{
-		Email:          params.Email,
+	now := time.Now().UTC()
+	err = cfg.db.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
 		HashedPassword: hashedPassword,
-		UpdatedAt:      time.Now().UTC(),
+		UpdatedAt:      now,
 		ID:             userID,
 	})
 	if err != nil {
@@ -108,11 +314,57 @@ func (cfg *apiConfig) handlerUpdateUserEmailAndPassword(w http.ResponseWriter, r
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+	// Access tokens don't say which session they belong to, so the caller's
+	// session is the refresh token they pass, if it is theirs. Without one,
+	// they get a new session instead.
+	keepRefreshToken := ""
+	if params.RefreshToken != "" {
+		dbRefreshToken, err := cfg.db.GetActiveRefreshToken(r.Context(), params.RefreshToken)
+		if err == nil && dbRefreshToken.UserID == userID && !dbRefreshToken.ClientID.Valid {
+			keepRefreshToken = dbRefreshToken.Token
+		}
+	}
+	err = revokeOtherCredentials(r.Context(), cfg.db, userID, keepRefreshToken)
+	if err != nil {
+		write500Error(w)
+		return
+	}
+
+	var accessToken, refreshToken string
+	if keepRefreshToken != "" {
+		refreshToken = keepRefreshToken
+		accessToken, err = auth.MakeJWT(userID, cfg.jwtSecret, time.Hour)
+	} else {
+		accessToken, refreshToken, err = cfg.issueTokens(r, userID)
+	}
+	if err != nil {
+		logRequestError(r, "Error issuing tokens", err)
+		write500Error(w)
+		return
+	}
+	type response struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	writeJSONResponse(w, http.StatusOK, response{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+// requestEmailChange sends a confirmation link to the new address and lets the
+// old address know a change was requested.
+func (cfg *apiConfig) requestEmailChange(r *http.Request, dbUser database.User, newEmail string) error {
+	err := cfg.sendEmailVerification(r, dbUser.ID, newEmail)
+	if err != nil {
+		return err
+	}
+	cfg.sendMailAsync(r.Context(), mailer.Message{
+		To:      dbUser.Email,
+		Subject: "Your Chirpy email address is being changed",
+		Body: "Someone asked to change the email address on your Chirpy account. " +
+			"The change only happens once it is confirmed from the new address.\n\n" +
+			"If this wasn't you, reset your password.\n",
 	})
+	return nil
 }
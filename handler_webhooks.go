@@ -1,10 +1,13 @@
 package main
 
 import (
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/dmytrochumakov/chirpy/internal/auth"
 	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/metrics"
 	"github.com/google/uuid"
 )
 
@@ -17,10 +20,14 @@ const (
 func (cfg *apiConfig) handlerWebhooks(w http.ResponseWriter, r *http.Request) {
 	apiKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
+		logRequestWarn(r, "Missing webhook API key", err)
+		cfg.metrics.ObserveWebhook("", metrics.WebhookOutcomeUnauthorized)
 		write401Error(w)
 		return
 	}
 	if apiKey != cfg.polkaKey {
+		slog.WarnContext(r.Context(), "Invalid webhook API key")
+		cfg.metrics.ObserveWebhook("", metrics.WebhookOutcomeUnauthorized)
 		write401Error(w)
 		return
 	}
@@ -34,26 +41,41 @@ func (cfg *apiConfig) handlerWebhooks(w http.ResponseWriter, r *http.Request) {
 	params := parameters{}
 	err = DecodeJSON(r, &params)
 	if err != nil {
+		logRequestWarn(r, "Error decoding webhook payload", err)
+		cfg.metrics.ObserveWebhook("", metrics.WebhookOutcomeBadRequest)
 		write500Error(w)
 		return
 	}
+	if params.Event != string(EventTypeUserUpgraded) {
+		cfg.metrics.ObserveWebhook("other", metrics.WebhookOutcomeIgnored)
+		writeStatusCodeResponse(w, http.StatusNoContent)
+		return
+	}
 	userID, err := uuid.Parse(params.Data.UserID)
 	if err != nil {
+		logRequestWarn(r, "Invalid webhook user ID", err)
+		cfg.metrics.ObserveWebhook(params.Event, metrics.WebhookOutcomeBadRequest)
 		write500Error(w)
 		return
 	}
-	if params.Event == string(EventTypeUserUpgraded) {
-		_, err := cfg.db.UpdateUserChirpyRedByUserID(r.Context(), database.UpdateUserChirpyRedByUserIDParams{
-			IsChirpyRed: true,
-			ID:          userID,
-		})
-		if err != nil {
-			write404Error(w)
-			return
-		}
-		writeStatusCodeResponse(w, http.StatusNoContent)
-	} else {
-		writeStatusCodeResponse(w, http.StatusNoContent)
+	_, err = cfg.db.UpdateUserChirpyRedByUserID(r.Context(), database.UpdateUserChirpyRedByUserIDParams{
+		IsChirpyRed: true,
+		ID:          userID,
+	})
+	if err != nil {
+		cfg.metrics.ObserveWebhook(params.Event, metrics.WebhookOutcomeUserNotFound)
+		write404Error(w)
 		return
 	}
+	err = cfg.db.CreateSubscriptionEvent(r.Context(), database.CreateSubscriptionEventParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UserID:    userID,
+		Event:     params.Event,
+	})
+	if err != nil {
+		logRequestError(r, "Error recording subscription event", err)
+	}
+	cfg.metrics.ObserveWebhook(params.Event, metrics.WebhookOutcomeProcessed)
+	writeStatusCodeResponse(w, http.StatusNoContent)
 }
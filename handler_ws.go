@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/entities"
+	"github.com/dmytrochumakov/chirpy/internal/ratelimit"
+	"github.com/dmytrochumakov/chirpy/internal/stream"
+	"github.com/google/uuid"
+)
+
+const (
+	wsAuthTimeout           = 10 * time.Second
+	wsWriteTimeout          = 10 * time.Second
+	wsPingInterval          = 30 * time.Second
+	wsMaxMessageBytes       = 8 << 10
+	wsMaxSubscriptions      = 20
+	wsMaxConnectionsPerUser = 5
+)
+
+type wsChannelKind string
+
+const (
+	wsChannelGlobal        wsChannelKind = "global"
+	wsChannelAuthor        wsChannelKind = "author"
+	wsChannelHome          wsChannelKind = "home"
+	wsChannelNotifications wsChannelKind = "notifications"
+)
+
+var (
+	errWSUnknownChannel = errors.New("unknown channel")
+	errWSTooSlow        = errors.New("connection fell behind")
+)
+
+// wsConnectionCounter counts each user's open WebSocket connections on this
+// replica. The zero value is ready to use.
+type wsConnectionCounter struct {
+	mu     sync.Mutex
+	counts map[uuid.UUID]int
+}
+
+func (c *wsConnectionCounter) acquire(userID uuid.UUID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = map[uuid.UUID]int{}
+	}
+	if c.counts[userID] >= wsMaxConnectionsPerUser {
+		return false
+	}
+	c.counts[userID]++
+	return true
+}
+
+func (c *wsConnectionCounter) release(userID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[userID]--
+	if c.counts[userID] <= 0 {
+		delete(c.counts, userID)
+	}
+}
+
+type wsClientMessage struct {
+	Type          string      `json:"type"`
+	ID            string      `json:"id"`
+	Token         string      `json:"token"`
+	Channel       string      `json:"channel"`
+	Body          string      `json:"body"`
+	AttachmentIDs []uuid.UUID `json:"attachment_ids"`
+}
+
+type wsServerMessage struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	Event   string `json:"event,omitempty"`
+	EventID int64  `json:"event_id,omitempty"`
+	Data    any    `json:"data,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type wsChannel struct {
+	Kind     wsChannelKind
+	AuthorID uuid.UUID
+}
+
+// handlerWebSocket serves the WebSocket API. Clients authenticate with an
+// access token, in the Authorization header or as the first message
+// ({"type": "auth", "token": "..."}), and then send:
+//
+//	{"type": "subscribe", "channel": "global" | "author:<user>" | "home" | "notifications"}
+//	{"type": "unsubscribe", "channel": "..."}
+//	{"type": "chirp", "body": "..."}
+//	{"type": "ping"}
+//
+// Any of them may carry an "id", which is echoed in the reply. Subscribed
+// events arrive as {"type": "event", "channel", "event", "event_id", "data"}
+// with the same data as GET /api/stream. There are no follows yet, so home
+// carries the user's own chirps and chirps mentioning them.
+//
+// Events wait in a small buffer per connection while a write is slow; a
+// connection that fills it is closed with status 1013 and should reconnect,
+// catching up through GET /api/stream or the REST listings.
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, r *http.Request) {
+	var userID uuid.UUID
+	if _, err := auth.GetBearerToken(r.Header); err == nil {
+		userID, err = cfg.authenticate(r)
+		if err != nil {
+			write401Error(w)
+			return
+		}
+	}
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		logRequestWarn(r, "Error accepting WebSocket connection", err)
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(wsMaxMessageBytes)
+
+	if userID == uuid.Nil {
+		userID, err = cfg.wsAuthenticate(r, conn)
+		if err != nil {
+			conn.Close(websocket.StatusPolicyViolation, "Authentication failed")
+			return
+		}
+	}
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		conn.Close(websocket.StatusPolicyViolation, "Authentication failed")
+		return
+	}
+	if !cfg.wsConnections.acquire(userID) {
+		conn.Close(websocket.StatusTryAgainLater, "Too many connections")
+		return
+	}
+	defer cfg.wsConnections.release(userID)
+
+	viewerID := uuid.NullUUID{UUID: userID, Valid: true}
+	blocked, err := cfg.streamHiddenAuthors(r.Context(), viewerID, true)
+	if err != nil {
+		conn.Close(websocket.StatusInternalError, "Something went wrong")
+		return
+	}
+	hiddenFromGlobal, err := cfg.streamHiddenAuthors(r.Context(), viewerID, false)
+	if err != nil {
+		conn.Close(websocket.StatusInternalError, "Something went wrong")
+		return
+	}
+
+	client := &wsClient{
+		cfg:              cfg,
+		r:                r,
+		conn:             conn,
+		dbUser:           dbUser,
+		blocked:          blocked,
+		hiddenFromGlobal: hiddenFromGlobal,
+		channels:         map[string]wsChannel{},
+		limiter:          ratelimit.NewMemoryStore(),
+	}
+	err = client.run()
+	if errors.Is(err, errWSTooSlow) {
+		conn.Close(websocket.StatusTryAgainLater, "Too slow to keep up")
+		return
+	}
+	if err != nil && websocket.CloseStatus(err) == -1 {
+		logRequestWarn(r, "WebSocket connection failed", err)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// wsAuthenticate reads the access token from the first message, for clients
+// such as browsers that can't set headers on the handshake.
+func (cfg *apiConfig) wsAuthenticate(r *http.Request, conn *websocket.Conn) (uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), wsAuthTimeout)
+	defer cancel()
+	msg := wsClientMessage{}
+	err := wsjson.Read(ctx, conn, &msg)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if msg.Type != "auth" {
+		return uuid.Nil, errors.New("first message is not auth")
+	}
+	return cfg.validateAccessToken(r, msg.Token)
+}
+
+type wsClient struct {
+	cfg  *apiConfig
+	r    *http.Request
+	conn *websocket.Conn
+
+	dbUser           database.User
+	blocked          map[uuid.UUID]bool
+	hiddenFromGlobal map[uuid.UUID]bool
+	channels         map[string]wsChannel
+	limiter          *ratelimit.MemoryStore
+}
+
+// run serves the connection until either side closes it. Only run writes
+// to the connection; a goroutine reads messages and hands them over.
+func (c *wsClient) run() error {
+	ctx, cancel := context.WithCancel(c.r.Context())
+	defer cancel()
+
+	chirpEvents := c.cfg.chirpStream.Subscribe(streamBufferSize)
+	defer chirpEvents.Close()
+	notificationEvents := c.cfg.notificationStream.Subscribe(streamBufferSize)
+	defer notificationEvents.Close()
+
+	messages := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			_, data, err := c.conn.Read(ctx)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case messages <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pingCtx, pingCancel := context.WithTimeout(ctx, wsWriteTimeout)
+				err := c.conn.Ping(pingCtx)
+				pingCancel()
+				if err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	type ready struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	err := c.write(ctx, wsServerMessage{
+		Type: "ready",
+		Data: ready{UserID: c.dbUser.ID},
+	})
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err = <-readErr:
+		case data := <-messages:
+			err = c.handleMessage(ctx, data)
+		case event, ok := <-chirpEvents.Events():
+			if !ok {
+				return errWSTooSlow
+			}
+			err = c.deliverChirpEvent(ctx, event)
+		case event, ok := <-notificationEvents.Events():
+			if !ok {
+				return errWSTooSlow
+			}
+			err = c.deliverNotification(ctx, event)
+		}
+	}
+	return err
+}
+
+func (c *wsClient) handleMessage(ctx context.Context, data []byte) error {
+	msg := wsClientMessage{}
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		return c.writeError(ctx, "", "Messages must be JSON objects")
+	}
+	res, err := c.limiter.Take(ctx, rateLimitWebSocketMessage.Name, rateLimitWebSocketMessage)
+	if err == nil && !res.Allowed {
+		return c.writeError(ctx, msg.ID, "Too many messages, slow down")
+	}
+	switch msg.Type {
+	case "subscribe":
+		return c.subscribe(ctx, msg)
+	case "unsubscribe":
+		delete(c.channels, msg.Channel)
+		return c.write(ctx, wsServerMessage{
+			Type:    "unsubscribed",
+			ID:      msg.ID,
+			Channel: msg.Channel,
+		})
+	case "chirp":
+		return c.postChirp(ctx, msg)
+	case "ping":
+		return c.write(ctx, wsServerMessage{
+			Type: "pong",
+			ID:   msg.ID,
+		})
+	default:
+		return c.writeError(ctx, msg.ID, "Unknown message type")
+	}
+}
+
+func (c *wsClient) subscribe(ctx context.Context, msg wsClientMessage) error {
+	if _, ok := c.channels[msg.Channel]; !ok && len(c.channels) >= wsMaxSubscriptions {
+		return c.writeError(ctx, msg.ID, fmt.Sprintf("At most %d subscriptions per connection", wsMaxSubscriptions))
+	}
+	channel, err := c.parseChannel(ctx, msg.Channel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.writeError(ctx, msg.ID, "User not found")
+	}
+	if errors.Is(err, errWSUnknownChannel) {
+		return c.writeError(ctx, msg.ID, "Channel must be global, author:<user>, home or notifications")
+	}
+	if err != nil {
+		return c.writeError(ctx, msg.ID, "Something went wrong")
+	}
+	c.channels[msg.Channel] = channel
+	return c.write(ctx, wsServerMessage{
+		Type:    "subscribed",
+		ID:      msg.ID,
+		Channel: msg.Channel,
+	})
+}
+
+func (c *wsClient) parseChannel(ctx context.Context, name string) (wsChannel, error) {
+	kind, arg, hasArg := strings.Cut(name, ":")
+	switch wsChannelKind(kind) {
+	case wsChannelGlobal, wsChannelHome, wsChannelNotifications:
+		if hasArg {
+			return wsChannel{}, errWSUnknownChannel
+		}
+		return wsChannel{Kind: wsChannelKind(kind)}, nil
+	case wsChannelAuthor:
+		authorID, err := c.cfg.resolveUserID(ctx, arg)
+		if err != nil {
+			return wsChannel{}, err
+		}
+		return wsChannel{Kind: wsChannelAuthor, AuthorID: authorID}, nil
+	default:
+		return wsChannel{}, errWSUnknownChannel
+	}
+}
+
+// postChirp makes the same checks as POST /api/chirps and shares its rate
+// limit.
+func (c *wsClient) postChirp(ctx context.Context, msg wsClientMessage) error {
+	key := rateLimitCreateChirp.Name + ":user:" + c.dbUser.ID.String()
+	res, err := c.cfg.rateLimiter.Take(ctx, key, rateLimitCreateChirp)
+	if err != nil {
+		logRequestError(c.r, "Error checking rate limit", err)
+	} else if !res.Allowed {
+		c.cfg.metrics.ObserveRateLimited(rateLimitCreateChirp.Name)
+		return c.writeError(ctx, msg.ID, "Too many chirps, try again later")
+	}
+
+	dbUser, err := c.cfg.db.GetUserByID(ctx, c.dbUser.ID)
+	if err != nil {
+		return c.writeError(ctx, msg.ID, "Something went wrong")
+	}
+	if !dbUser.EmailVerified {
+		return c.writeError(ctx, msg.ID, "Email address must be verified before posting chirps")
+	}
+	if isSuspended(dbUser, time.Now().UTC()) {
+		return c.writeError(ctx, msg.ID, "Account is suspended")
+	}
+	dbChirp, err := c.cfg.createChirp(c.r, dbUser.ID, msg.Body, msg.AttachmentIDs)
+	if errors.Is(err, errInvalidAttachments) {
+		return c.writeError(ctx, msg.ID, "attachment_ids must be at most 4 of your uploads not yet attached to a chirp")
+	}
+	if err != nil {
+		return c.writeError(ctx, msg.ID, "Something went wrong")
+	}
+	chirps, err := c.cfg.chirpResponses(ctx, []database.Chirp{dbChirp})
+	if err != nil {
+		return c.writeError(ctx, msg.ID, "Something went wrong")
+	}
+	return c.write(ctx, wsServerMessage{
+		Type: "chirp_posted",
+		ID:   msg.ID,
+		Data: chirps[0],
+	})
+}
+
+func (c *wsClient) deliverChirpEvent(ctx context.Context, event stream.Event) error {
+	if c.blocked[event.UserID] {
+		return nil
+	}
+	for name, channel := range c.channels {
+		if !c.wantsChirpEvent(channel, event) {
+			continue
+		}
+		err := c.write(ctx, wsServerMessage{
+			Type:    "event",
+			Channel: name,
+			Event:   event.Type,
+			EventID: event.ID,
+			Data:    json.RawMessage(event.Data),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *wsClient) wantsChirpEvent(channel wsChannel, event stream.Event) bool {
+	switch channel.Kind {
+	case wsChannelGlobal:
+		return !c.hiddenFromGlobal[event.UserID]
+	case wsChannelAuthor:
+		return event.UserID == channel.AuthorID
+	case wsChannelHome:
+		return event.UserID == c.dbUser.ID || c.mentionsUser(event)
+	default:
+		return false
+	}
+}
+
+func (c *wsClient) mentionsUser(event stream.Event) bool {
+	if !c.dbUser.Handle.Valid {
+		return false
+	}
+	chirp := Chirp{}
+	err := json.Unmarshal(event.Data, &chirp)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(entities.Mentions(chirp.Body), strings.ToLower(c.dbUser.Handle.String))
+}
+
+func (c *wsClient) deliverNotification(ctx context.Context, event stream.Event) error {
+	if event.UserID != c.dbUser.ID {
+		return nil
+	}
+	name := string(wsChannelNotifications)
+	if _, ok := c.channels[name]; !ok {
+		return nil
+	}
+	return c.write(ctx, wsServerMessage{
+		Type:    "event",
+		Channel: name,
+		Event:   event.Type,
+		Data:    json.RawMessage(event.Data),
+	})
+}
+
+func (c *wsClient) write(ctx context.Context, msg wsServerMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+	defer cancel()
+	return wsjson.Write(ctx, c.conn, msg)
+}
+
+func (c *wsClient) writeError(ctx context.Context, id, message string) error {
+	return c.write(ctx, wsServerMessage{
+		Type:  "error",
+		ID:    id,
+		Error: message,
+	})
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"path"
 	"strings"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
 )
 
 func handlerHealthz(w http.ResponseWriter, r *http.Request) {
@@ -14,7 +19,34 @@ func handlerHealthz(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request) {
+// noListingFS keeps the file server from listing directories: one without
+// an index.html is not found.
+type noListingFS struct {
+	fs http.FileSystem
+}
+
+func (n noListingFS) Open(name string) (http.File, error) {
+	file, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		index, err := n.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			file.Close()
+			return nil, fs.ErrNotExist
+		}
+		index.Close()
+	}
+	return file, nil
+}
+
+func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request, actor database.User) {
 	html := `
 		<html>
 		<body>
@@ -28,13 +60,16 @@ func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(fmt.Sprintf(html, cfg.fileserverHits.Load())))
 }
 
-func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
+// handlerReset wipes every user. Even admins may only do that on a
+// development deployment.
+func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request, actor database.User) {
 	if cfg.envPlatform != "dev" {
 		write403Error(w)
 		return
 	}
 	err := cfg.db.DeleteAllUsers(r.Context())
 	if err != nil {
+		logRequestError(r, "Error deleting users", err)
 		write500Error(w)
 		return
 	}
@@ -53,19 +88,34 @@ func handlerValidateChirp(w http.ResponseWriter, r *http.Request) {
 	err := decoder.Decode(&params)
 
 	if err != nil {
-		log.Printf("Error decoding parameters %s", err)
+		logRequestWarn(r, "Error decoding parameters", err)
 		write500Error(w)
 		return
 	}
 
-	if len(params.Body) > 140 {
-		log.Println("params.Body is too long")
+	cleanedBody, err := cleanChirpBody(params.Body)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Chirp is too long", "length", len(params.Body))
 		writeError(w, 400, "Chirp is too long")
 		return
 	}
+	writeCleanedBody(w, cleanedBody)
+}
+
+const maxChirpLength = 140
 
-	profaneWords := []string{"kerfuffle", "sharbert", "fornax"}
-	splitBody := strings.Split(params.Body, " ")
+var (
+	errChirpTooLong = errors.New("chirp is too long")
+	profaneWords    = []string{"kerfuffle", "sharbert", "fornax"}
+)
+
+// cleanChirpBody checks the length of a chirp, or of a direct message, and
+// masks profane words.
+func cleanChirpBody(body string) (string, error) {
+	if len(body) > maxChirpLength {
+		return "", errChirpTooLong
+	}
+	splitBody := strings.Split(body, " ")
 	cleanedBodyArray := []string{}
 
 	for _, str := range splitBody {
@@ -77,6 +127,5 @@ func handlerValidateChirp(w http.ResponseWriter, r *http.Request) {
 		}
 		cleanedBodyArray = append(cleanedBodyArray, newStr)
 	}
-	cleanedBody := strings.Join(cleanedBodyArray, " ")
-	writeCleanedBody(w, cleanedBody)
+	return strings.Join(cleanedBodyArray, " "), nil
 }
@@ -15,6 +15,7 @@ type TokenType string
 
 const (
 	TokenTypeAccess TokenType = "chirpy-access"
+	TokenTypeMFA    TokenType = "chirpy-mfa"
 )
 
 var ErrNoAuthHeaderIncluded = errors.New("no auth header included in request")
@@ -24,17 +25,55 @@ func MakeJWT(
 	tokenSecret string,
 	expiresIn time.Duration,
 ) (string, error) {
-	signingKey := []byte(tokenSecret)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    string(TokenTypeAccess),
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+	return makeToken(TokenTypeAccess, userID, tokenSecret, expiresIn)
+}
+
+// MakeMFAToken issues the challenge token returned by the first login step to
+// users with two-factor authentication enabled. It can't be used as an access
+// token.
+func MakeMFAToken(
+	userID uuid.UUID,
+	tokenSecret string,
+	expiresIn time.Duration,
+) (string, error) {
+	return makeToken(TokenTypeMFA, userID, tokenSecret, expiresIn)
+}
+
+func makeToken(
+	tokenType TokenType,
+	userID uuid.UUID,
+	tokenSecret string,
+	expiresIn time.Duration,
+) (string, error) {
+	return signToken(registeredClaims(tokenType, userID, expiresIn), tokenSecret)
+}
+
+// registeredClaims are the claims every token type starts from: the type as
+// issuer and the user as subject.
+func registeredClaims(tokenType TokenType, userID uuid.UUID, expiresIn time.Duration) jwt.RegisteredClaims {
+	now := time.Now().UTC()
+	return jwt.RegisteredClaims{
+		Issuer:    string(tokenType),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
 		Subject:   userID.String(),
-	})
-	return token.SignedString(signingKey)
+	}
+}
+
+func signToken(claims jwt.Claims, tokenSecret string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(tokenSecret))
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateToken(TokenTypeAccess, tokenString, tokenSecret)
+}
+
+func ValidateMFAToken(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateToken(TokenTypeMFA, tokenString, tokenSecret)
+}
+
+func validateToken(tokenType TokenType, tokenString, tokenSecret string) (uuid.UUID, error) {
 	claimsStruct := jwt.RegisteredClaims{}
 	token, err := jwt.ParseWithClaims(
 		tokenString,
@@ -54,7 +93,7 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	if err != nil {
 		return uuid.Nil, err
 	}
-	if issuer != string(TokenTypeAccess) {
+	if issuer != string(tokenType) {
 		return uuid.Nil, errors.New("invalid issuer")
 	}
 
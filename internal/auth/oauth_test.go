@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// The example from RFC 7636 appendix B.
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		challenge string
+		verifier  string
+		want      bool
+	}{
+		{name: "RFC example", challenge: challenge, verifier: verifier, want: true},
+		{name: "wrong verifier", challenge: challenge, verifier: strings.Replace(verifier, "d", "e", 1), want: false},
+		{name: "plain method", challenge: verifier, verifier: verifier, want: false},
+		{name: "padded challenge", challenge: challenge + "=", verifier: verifier, want: false},
+		{name: "empty verifier", challenge: challenge, verifier: "", want: false},
+		{name: "verifier too short", challenge: challenge, verifier: verifier[:42], want: false},
+		{name: "verifier too long", challenge: challenge, verifier: strings.Repeat("a", 129), want: false},
+		{name: "verifier with invalid characters", challenge: challenge, verifier: verifier[:42] + "+", want: false},
+		{name: "empty challenge", challenge: "", verifier: verifier, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.challenge, tt.verifier); got != tt.want {
+				t.Errorf("VerifyPKCE(%q, %q) = %v, want %v", tt.challenge, tt.verifier, got, tt.want)
+			}
+		})
+	}
+}
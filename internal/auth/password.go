@@ -1,18 +1,164 @@
 package auth
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+)
+
+const tracerName = "github.com/dmytrochumakov/chirpy/internal/auth"
+
+// legacyUnsetHash is the default migration 003 gave every user that existed
+// before passwords did. It matches no password; those users have to reset.
+const legacyUnsetHash = "unset"
+
+var (
+	ErrPasswordMismatch  = errors.New("password does not match")
+	ErrPasswordNotSet    = errors.New("user has no password set")
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+)
+
+// PasswordHasher is one password hashing scheme.
+type PasswordHasher interface {
+	// Hash encodes password with a fresh random salt.
+	Hash(password string) (string, error)
+	// Recognizes reports whether encoded was produced by this scheme.
+	Recognizes(encoded string) bool
+	// Verify checks password against encoded, which must be recognized by
+	// this scheme, and reports whether encoded uses outdated parameters.
+	Verify(encoded, password string) (outdated bool, err error)
+}
+
+var (
+	passwordHasher PasswordHasher = NewArgon2idHasher(DefaultArgon2idParams)
+	// knownHashers verify hashes written by a scheme other than the current
+	// one, such as the bcrypt hashes from before argon2id. Both schemes keep
+	// their parameters in the encoded hash, so the defaults here don't matter.
+	knownHashers = []PasswordHasher{
+		NewArgon2idHasher(DefaultArgon2idParams),
+		NewBcryptHasher(DefaultBcryptCost),
+	}
+	// dummyHash is what unknown users' passwords are checked against, so a
+	// login for an email that doesn't exist costs about as much as one for a
+	// user whose hash uses the current scheme. Users still on another scheme
+	// take that scheme's time instead until their next login rehashes them,
+	// which can tell them apart from unknown emails.
+	dummyHash = newDummyHash(passwordHasher)
 )
 
-func HashPassword(password string) (string, error) {
-	data, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func newDummyHash(hasher PasswordHasher) func() string {
+	return sync.OnceValue(func() string {
+		hash, _ := hasher.Hash("chirpy-dummy-password")
+		return hash
+	})
+}
+
+// SetPasswordHasher changes the scheme new hashes are written with. Hashes
+// from any other scheme, or with other parameters, are reported as outdated
+// by CheckPasswordHash. It must be called before serving requests.
+func SetPasswordHasher(hasher PasswordHasher) {
+	passwordHasher = hasher
+	dummyHash = newDummyHash(hasher)
+}
+
+type PasswordHasherType string
+
+const (
+	PasswordHasherArgon2id PasswordHasherType = "argon2id"
+	PasswordHasherBcrypt   PasswordHasherType = "bcrypt"
+)
 
-	if err != nil {
-		return "", err
+// PasswordHasherFromEnv builds the hasher selected by PASSWORD_HASHER.
+// Argon2id is tuned with ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and
+// ARGON2_PARALLELISM; bcrypt with BCRYPT_COST. Unset values keep the defaults.
+func PasswordHasherFromEnv() (PasswordHasher, error) {
+	switch PasswordHasherType(os.Getenv("PASSWORD_HASHER")) {
+	case "", PasswordHasherArgon2id:
+		memory, err := uintFromEnv("ARGON2_MEMORY_KIB", uint64(DefaultArgon2idParams.Memory), 32)
+		if err != nil {
+			return nil, err
+		}
+		iterations, err := uintFromEnv("ARGON2_ITERATIONS", uint64(DefaultArgon2idParams.Iterations), 32)
+		if err != nil {
+			return nil, err
+		}
+		parallelism, err := uintFromEnv("ARGON2_PARALLELISM", uint64(DefaultArgon2idParams.Parallelism), 8)
+		if err != nil {
+			return nil, err
+		}
+		params := DefaultArgon2idParams
+		params.Memory = uint32(memory)
+		params.Iterations = uint32(iterations)
+		params.Parallelism = uint8(parallelism)
+		return NewArgon2idHasher(params), nil
+	case PasswordHasherBcrypt:
+		cost, err := uintFromEnv("BCRYPT_COST", uint64(DefaultBcryptCost), 8)
+		if err != nil {
+			return nil, err
+		}
+		return NewBcryptHasher(int(cost)), nil
+	default:
+		return nil, fmt.Errorf("unknown password hasher: %s", os.Getenv("PASSWORD_HASHER"))
 	}
-	return string(data), nil
 }
 
-func CheckPasswordHash(hash, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+func uintFromEnv(key string, fallback uint64, bitSize int) (uint64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseUint(value, 10, bitSize)
+	if err != nil || parsed == 0 {
+		return 0, fmt.Errorf("invalid %s: %q", key, value)
+	}
+	return parsed, nil
+}
+
+func HashPassword(ctx context.Context, password string) (string, error) {
+	_, span := otel.Tracer(tracerName).Start(ctx, "auth.HashPassword")
+	defer span.End()
+
+	return passwordHasher.Hash(password)
+}
+
+// CheckPasswordHash verifies password against hash. On success it also
+// reports whether hash should be replaced with a fresh HashPassword, because
+// it was written by another scheme or with weaker parameters.
+func CheckPasswordHash(ctx context.Context, hash, password string) (bool, error) {
+	_, span := otel.Tracer(tracerName).Start(ctx, "auth.CheckPasswordHash")
+	defer span.End()
+
+	if passwordHasher.Recognizes(hash) {
+		return passwordHasher.Verify(hash, password)
+	}
+	for _, hasher := range knownHashers {
+		if hasher.Recognizes(hash) {
+			_, err := hasher.Verify(hash, password)
+			if err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+
+	passwordHasher.Verify(dummyHash(), password)
+	if hash == legacyUnsetHash || hash == "" {
+		return false, ErrPasswordNotSet
+	}
+	return false, ErrUnknownHashFormat
+}
+
+// CheckPasswordUnknownUser burns the hashing work of one verification with
+// the current scheme for a login whose email matched no user. Only users
+// whose hashes use that scheme take the same time; see dummyHash.
+func CheckPasswordUnknownUser(ctx context.Context, password string) {
+	_, span := otel.Tracer(tracerName).Start(ctx, "auth.CheckPasswordHash")
+	defer span.End()
+
+	passwordHasher.Verify(dummyHash(), password)
 }
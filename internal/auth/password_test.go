@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams keep the tests fast; they are far too weak for real use.
+var testArgon2idParams = Argon2idParams{
+	Memory:      64,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  8,
+	KeyLength:   16,
+}
+
+func useTestPasswordHasher(t *testing.T, hasher PasswordHasher) {
+	t.Helper()
+	oldHasher, oldDummyHash := passwordHasher, dummyHash
+	SetPasswordHasher(hasher)
+	t.Cleanup(func() {
+		passwordHasher, dummyHash = oldHasher, oldDummyHash
+	})
+}
+
+func mustHash(t *testing.T, hasher PasswordHasher, password string) string {
+	t.Helper()
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	return hash
+}
+
+func TestDecodeArgon2id(t *testing.T) {
+	const salt = "c2FsdHNhbHQ"
+	const key = "a2V5a2V5a2V5a2V5a2V5aw"
+
+	tests := []struct {
+		name    string
+		encoded string
+		want    Argon2idParams
+		wantErr error
+	}{
+		{
+			name:    "valid",
+			encoded: "$argon2id$v=19$m=65536,t=3,p=4$" + salt + "$" + key,
+			want:    Argon2idParams{Memory: 65536, Iterations: 3, Parallelism: 4, SaltLength: 8, KeyLength: 16},
+		},
+		{name: "argon2i", encoded: "$argon2i$v=19$m=65536,t=3,p=4$" + salt + "$" + key, wantErr: ErrUnknownHashFormat},
+		{name: "missing key", encoded: "$argon2id$v=19$m=65536,t=3,p=4$" + salt, wantErr: ErrUnknownHashFormat},
+		{name: "empty key", encoded: "$argon2id$v=19$m=65536,t=3,p=4$" + salt + "$", wantErr: ErrUnknownHashFormat},
+		{name: "empty salt", encoded: "$argon2id$v=19$m=65536,t=3,p=4$$" + key, wantErr: ErrUnknownHashFormat},
+		{name: "zero iterations", encoded: "$argon2id$v=19$m=65536,t=0,p=4$" + salt + "$" + key, wantErr: ErrUnknownHashFormat},
+		{name: "zero parallelism", encoded: "$argon2id$v=19$m=65536,t=3,p=0$" + salt + "$" + key, wantErr: ErrUnknownHashFormat},
+		{name: "too little memory per lane", encoded: "$argon2id$v=19$m=31,t=3,p=4$" + salt + "$" + key, wantErr: ErrUnknownHashFormat},
+		{
+			name:    "least memory per lane",
+			encoded: "$argon2id$v=19$m=32,t=3,p=4$" + salt + "$" + key,
+			want:    Argon2idParams{Memory: 32, Iterations: 3, Parallelism: 4, SaltLength: 8, KeyLength: 16},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, _, err := decodeArgon2id(tt.encoded)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decodeArgon2id() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeArgon2id() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	malformed := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "other version", encoded: "$argon2id$v=16$m=65536,t=3,p=4$" + salt + "$" + key},
+		{name: "garbled parameters", encoded: "$argon2id$v=19$m=lots,t=3,p=4$" + salt + "$" + key},
+		{name: "parallelism overflow", encoded: "$argon2id$v=19$m=65536,t=3,p=256$" + salt + "$" + key},
+		{name: "padded salt", encoded: "$argon2id$v=19$m=65536,t=3,p=4$" + salt + "=$" + key},
+		{name: "invalid key", encoded: "$argon2id$v=19$m=65536,t=3,p=4$" + salt + "$!!"},
+	}
+	for _, tt := range malformed {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := decodeArgon2id(tt.encoded); err == nil {
+				t.Error("decodeArgon2id() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestArgon2idVerify(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+	hash := mustHash(t, hasher, "correct horse")
+
+	tests := []struct {
+		name         string
+		hasher       *Argon2idHasher
+		encoded      string
+		password     string
+		wantOutdated bool
+		wantErr      error
+	}{
+		{name: "match", hasher: hasher, encoded: hash, password: "correct horse"},
+		{name: "mismatch", hasher: hasher, encoded: hash, password: "battery staple", wantErr: ErrPasswordMismatch},
+		{name: "weaker than current", hasher: NewArgon2idHasher(stronger), encoded: hash, password: "correct horse", wantOutdated: true},
+		{
+			name:     "empty key doesn't match",
+			hasher:   hasher,
+			encoded:  hash[:strings.LastIndex(hash, "$")+1],
+			password: "anything",
+			wantErr:  ErrUnknownHashFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outdated, err := tt.hasher.Verify(tt.encoded, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if outdated != tt.wantOutdated {
+				t.Errorf("Verify() outdated = %v, want %v", outdated, tt.wantOutdated)
+			}
+		})
+	}
+}
+
+func TestCheckPasswordHash(t *testing.T) {
+	argon2id := NewArgon2idHasher(testArgon2idParams)
+	weaker := testArgon2idParams
+	weaker.KeyLength = 8
+	bcryptHasher := NewBcryptHasher(bcrypt.MinCost)
+
+	tests := []struct {
+		name         string
+		current      PasswordHasher
+		hash         string
+		password     string
+		wantOutdated bool
+		wantErr      error
+	}{
+		{name: "current scheme", current: argon2id, hash: mustHash(t, argon2id, "hunter2"), password: "hunter2"},
+		{name: "current scheme, wrong password", current: argon2id, hash: mustHash(t, argon2id, "hunter2"), password: "hunter3", wantErr: ErrPasswordMismatch},
+		{name: "older parameters", current: argon2id, hash: mustHash(t, NewArgon2idHasher(weaker), "hunter2"), password: "hunter2", wantOutdated: true},
+		{name: "bcrypt to argon2id", current: argon2id, hash: mustHash(t, bcryptHasher, "hunter2"), password: "hunter2", wantOutdated: true},
+		{name: "bcrypt, wrong password", current: argon2id, hash: mustHash(t, bcryptHasher, "hunter2"), password: "hunter3", wantErr: ErrPasswordMismatch},
+		{name: "argon2id to bcrypt", current: bcryptHasher, hash: mustHash(t, argon2id, "hunter2"), password: "hunter2", wantOutdated: true},
+		{name: "bcrypt current", current: bcryptHasher, hash: mustHash(t, bcryptHasher, "hunter2"), password: "hunter2"},
+		{name: "legacy unset", current: argon2id, hash: legacyUnsetHash, password: "unset", wantErr: ErrPasswordNotSet},
+		{name: "empty hash", current: argon2id, hash: "", password: "", wantErr: ErrPasswordNotSet},
+		{name: "unknown scheme", current: argon2id, hash: "$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5", password: "hunter2", wantErr: ErrUnknownHashFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestPasswordHasher(t, tt.current)
+			outdated, err := CheckPasswordHash(context.Background(), tt.hash, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckPasswordHash() error = %v, want %v", err, tt.wantErr)
+			}
+			if outdated != tt.wantOutdated {
+				t.Errorf("CheckPasswordHash() outdated = %v, want %v", outdated, tt.wantOutdated)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scope limits what a personal access token may do. Access tokens from a
+// login carry every scope.
+type Scope string
+
+const (
+	ScopeChirpsRead   Scope = "chirps:read"
+	ScopeChirpsWrite  Scope = "chirps:write"
+	ScopeProfileWrite Scope = "profile:write"
+)
+
+var Scopes = []Scope{ScopeChirpsRead, ScopeChirpsWrite, ScopeProfileWrite}
+
+// PersonalAccessTokenPrefix marks personal access tokens so they can be told
+// apart from JWTs without a database lookup, and found by secret scanners.
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+func MakePersonalAccessToken() (string, error) {
+	token, err := MakeOneTimeToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalAccessTokenPrefix + token, nil
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// ParseScopes checks that every requested scope exists and drops duplicates.
+func ParseScopes(requested []string) ([]string, error) {
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, scope := range requested {
+		if !slices.Contains(Scopes, Scope(scope)) {
+			return nil, fmt.Errorf("unknown scope: %s", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 )
 
@@ -15,3 +16,19 @@ func MakeRefreshToken() (string, error) {
 	encodedStr := hex.EncodeToString(b)
 	return encodedStr, nil
 }
+
+// MakeOneTimeToken returns a random token for links sent by email. Only its
+// HashToken digest should be stored.
+func MakeOneTimeToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,58 @@
+// Package blob stores uploaded files, such as chirp attachments, behind an
+// interface so that the backend can change without touching the handlers.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store holds blobs under slash-separated keys chosen by the application.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get returns ErrNotFound for keys that were never put or were deleted.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds for keys that don't exist.
+	Delete(ctx context.Context, key string) error
+	// URL is where clients download the blob from.
+	URL(key string) string
+}
+
+type Type string
+
+const (
+	TypeLocal Type = "local"
+)
+
+// FromEnv builds the store selected by BLOB_STORE. The local store writes to
+// BLOB_DIR, ~/.chirpy/uploads by default: anywhere under the working
+// directory would be published by the /app/ file server, metadata-laden
+// uploads included. Clients fetch blobs from baseURL/api/blobs, where the
+// API checks they may be seen, unless BLOB_BASE_URL points elsewhere.
+func FromEnv(baseURL string) (Store, error) {
+	switch Type(os.Getenv("BLOB_STORE")) {
+	case "", TypeLocal:
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("BLOB_DIR is not set and there is no home directory to default to: %w", err)
+			}
+			dir = filepath.Join(home, ".chirpy", "uploads")
+		}
+		blobBaseURL := os.Getenv("BLOB_BASE_URL")
+		if blobBaseURL == "" {
+			blobBaseURL = strings.TrimSuffix(baseURL, "/") + "/api/blobs"
+		}
+		return NewLocalStore(dir, blobBaseURL)
+	default:
+		return nil, fmt.Errorf("unknown blob store: %s", os.Getenv("BLOB_STORE"))
+	}
+}
@@ -0,0 +1,194 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestLocalStore(t *testing.T) *LocalStore {
+	t.Helper()
+	s, err := NewLocalStore(filepath.Join(t.TempDir(), "uploads"), "https://chirpy.example/api/blobs/")
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	return s
+}
+
+func readBlob(t *testing.T, s *LocalStore, key string) ([]byte, error) {
+	t.Helper()
+	reader, err := s.Get(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func TestLocalStoreKeys(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{key: "a.png", valid: true},
+		{key: "attachments/123/original.png", valid: true},
+		{key: "incoming/123.png", valid: true},
+		{key: "", valid: false},
+		{key: "../outside.png", valid: false},
+		{key: "attachments/../../outside.png", valid: false},
+		{key: "/etc/passwd", valid: false},
+		{key: "..", valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			s := newTestLocalStore(t)
+			ctx := context.Background()
+			checks := map[string]error{
+				"Put":    s.Put(ctx, tt.key, strings.NewReader("data"), "image/png"),
+				"Delete": s.Delete(ctx, tt.key),
+			}
+			_, checks["Get"] = s.Get(ctx, tt.key)
+			for op, err := range checks {
+				if tt.valid && err != nil && !errors.Is(err, ErrNotFound) {
+					t.Errorf("%s(%q) error = %v, want nil", op, tt.key, err)
+				}
+				if !tt.valid && (err == nil || errors.Is(err, ErrNotFound)) {
+					t.Errorf("%s(%q) error = %v, want an invalid key error", op, tt.key, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(s.dir), "outside.png")); err == nil {
+				t.Errorf("Put(%q) wrote outside the store", tt.key)
+			}
+		})
+	}
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+	const key = "attachments/123/original.png"
+
+	if _, err := readBlob(t, s, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() before Put error = %v, want ErrNotFound", err)
+	}
+	err := s.Put(ctx, key, strings.NewReader("first"), "image/png")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	err = s.Put(ctx, key, strings.NewReader("second"), "image/png")
+	if err != nil {
+		t.Fatalf("Put() overwrite error = %v", err)
+	}
+	got, err := readBlob(t, s, key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(got) != "second" {
+		t.Errorf("Get() = %q, want %q", got, "second")
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, "attachments", "123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("store directory holds %d files, want only the blob", len(entries))
+	}
+
+	err = s.Delete(ctx, key)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := readBlob(t, s, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+	err = s.Delete(ctx, key)
+	if err != nil {
+		t.Errorf("Delete() of a missing blob error = %v, want nil", err)
+	}
+}
+
+// errReader fails part way through, like an upload cut off by the client.
+type errReader struct {
+	data []byte
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestLocalStoreFailedPut(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+	const key = "incoming/123.png"
+
+	err := s.Put(ctx, key, strings.NewReader("complete"), "image/png")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	err = s.Put(ctx, key, &errReader{data: []byte("partial")}, "image/png")
+	if err == nil {
+		t.Fatal("Put() from a failing reader error = nil, want an error")
+	}
+	got, err := readBlob(t, s, key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(got) != "complete" {
+		t.Errorf("Get() after a failed Put = %q, want the earlier blob", got)
+	}
+	entries, err := os.ReadDir(filepath.Join(s.dir, "incoming"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("incoming/ holds %d files, want no temporary files left", len(entries))
+	}
+}
+
+// TestLocalStorePromotion follows an upload from incoming/, where it can't
+// be served, to the key processing stores it under.
+func TestLocalStorePromotion(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+	const incomingKey = "incoming/123.png"
+	const servableKey = "attachments/123/original.png"
+	data := []byte("\x89PNG processed")
+
+	err := s.Put(ctx, incomingKey, strings.NewReader("\x89PNG upload"), "image/png")
+	if err != nil {
+		t.Fatalf("Put(%q) error = %v", incomingKey, err)
+	}
+	err = s.Put(ctx, servableKey, bytes.NewReader(data), "image/png")
+	if err != nil {
+		t.Fatalf("Put(%q) error = %v", servableKey, err)
+	}
+	err = s.Delete(ctx, incomingKey)
+	if err != nil {
+		t.Fatalf("Delete(%q) error = %v", incomingKey, err)
+	}
+
+	if _, err := readBlob(t, s, incomingKey); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(%q) error = %v, want ErrNotFound", incomingKey, err)
+	}
+	got, err := readBlob(t, s, servableKey)
+	if err != nil {
+		t.Fatalf("Get(%q) error = %v", servableKey, err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Get(%q) = %q, want %q", servableKey, got, data)
+	}
+	if got, want := s.URL(servableKey), "https://chirpy.example/api/blobs/"+servableKey; got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+}
@@ -27,7 +27,7 @@ VALUES(
     $4,
     $5
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, moderation_status
 `
 
 type CreateChirpParams struct {
@@ -53,6 +53,7 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ModerationStatus,
 	)
 	return i, err
 }
@@ -68,7 +69,7 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, moderation_status FROM chirps
 ORDER BY created_at
 `
 
@@ -87,6 +88,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ModerationStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -102,7 +104,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpByChirpIDAndUserID = `-- name: GetChirpByChirpIDAndUserID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, moderation_status FROM chirps
 WHERE id = $1 AND user_id = $2
 `
 
@@ -120,12 +122,13 @@ func (q *Queries) GetChirpByChirpIDAndUserID(ctx context.Context, arg GetChirpBy
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ModerationStatus,
 	)
 	return i, err
 }
 
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, moderation_status FROM chirps
 WHERE id = $1
 `
 
@@ -138,12 +141,13 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ModerationStatus,
 	)
 	return i, err
 }
 
 const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, moderation_status FROM chirps
 WHERE user_id = $1
 ORDER BY created_at
 `
@@ -163,6 +167,7 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ModerationStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -176,3 +181,161 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const getVisibleChirpByID = `-- name: GetVisibleChirpByID :one
+SELECT id, created_at, updated_at, body, user_id, moderation_status FROM chirps
+WHERE id = $1
+AND (
+    moderation_status = 'visible'
+    OR (moderation_status = 'hidden' AND user_id = $2)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = chirps.user_id AND blocked_id = $2)
+    OR (blocker_id = $2 AND blocked_id = chirps.user_id)
+)
+`
+
+type GetVisibleChirpByIDParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetVisibleChirpByID(ctx context.Context, arg GetVisibleChirpByIDParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleChirpByID, arg.ID, arg.ViewerID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ModerationStatus,
+	)
+	return i, err
+}
+
+const getVisibleChirps = `-- name: GetVisibleChirps :many
+SELECT id, created_at, updated_at, body, user_id, moderation_status FROM chirps
+WHERE (
+    moderation_status = 'visible'
+    OR (moderation_status = 'hidden' AND user_id = $1)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = chirps.user_id AND blocked_id = $1)
+    OR (blocker_id = $1 AND blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE muter_id = $1 AND muted_id = chirps.user_id
+)
+ORDER BY created_at
+`
+
+func (q *Queries) GetVisibleChirps(ctx context.Context, viewerID uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getVisibleChirps, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleChirpsByUserID = `-- name: GetVisibleChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, moderation_status FROM chirps
+WHERE user_id = $1
+AND (
+    moderation_status = 'visible'
+    OR (moderation_status = 'hidden' AND user_id = $2)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = chirps.user_id AND blocked_id = $2)
+    OR (blocker_id = $2 AND blocked_id = chirps.user_id)
+)
+ORDER BY created_at
+`
+
+type GetVisibleChirpsByUserIDParams struct {
+	UserID   uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetVisibleChirpsByUserID(ctx context.Context, arg GetVisibleChirpsByUserIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getVisibleChirpsByUserID, arg.UserID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setChirpModerationStatus = `-- name: SetChirpModerationStatus :one
+UPDATE chirps
+SET moderation_status = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, body, user_id, moderation_status
+`
+
+type SetChirpModerationStatusParams struct {
+	ModerationStatus string
+	UpdatedAt        time.Time
+	ID               uuid.UUID
+}
+
+func (q *Queries) SetChirpModerationStatus(ctx context.Context, arg SetChirpModerationStatusParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpModerationStatus, arg.ModerationStatus, arg.UpdatedAt, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ModerationStatus,
+	)
+	return i, err
+}
@@ -11,28 +11,300 @@ import (
 	"github.com/google/uuid"
 )
 
+type Attachment struct {
+	ID          uuid.UUID
+	UserID      uuid.NullUUID
+	ChirpID     uuid.NullUUID
+	StorageKey  string
+	ContentType string
+	Size        int64
+	Position    int32
+	CreatedAt   time.Time
+	Status      string
+	Width       sql.NullInt32
+	Height      sql.NullInt32
+	Blurhash    sql.NullString
+	Error       sql.NullString
+	ProcessedAt sql.NullTime
+	AttachedAt  sql.NullTime
+	ClaimedAt   sql.NullTime
+	Attempts    int32
+}
+
+type AttachmentVariant struct {
+	AttachmentID uuid.UUID
+	Name         string
+	StorageKey   string
+	ContentType  string
+	Width        int32
+	Height       int32
+	Size         int64
+}
+
 type Chirp struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Body             string
+	UserID           uuid.UUID
+	ModerationStatus string
+}
+
+type ChirpEvent struct {
+	ID        int64
+	Type      string
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+type ChirpReport struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	Details    string
+	ResolvedAt sql.NullTime
+}
+
+type Conversation struct {
 	ID        uuid.UUID
+	DirectKey sql.NullString
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+}
+
+type DataExport struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UserID      uuid.UUID
+	Status      string
+	Archive     []byte
+	Error       sql.NullString
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	CreatedAt time.Time
 	UserID    uuid.UUID
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 }
 
-type RefreshToken struct {
-	Token     string
+type LoginFailure struct {
+	Subject      string
+	Failures     int32
+	LastFailedAt time.Time
+	BlockedUntil sql.NullTime
+}
+
+type LoginLockout struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	Subject     string
+	IpAddress   string
+	Failures    int32
+	LockedUntil time.Time
+}
+
+type Message struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+	CreatedAt      time.Time
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	ModeratorID  uuid.NullUUID
+	TargetUserID uuid.UUID
+	ChirpID      uuid.NullUUID
+	Action       string
+	Note         string
+}
+
+type ModerationAppeal struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ActionID       uuid.UUID
+	UserID         uuid.UUID
+	Message        string
+	Status         string
+	ResolvedAt     sql.NullTime
+	ResolverID     uuid.NullUUID
+	ResolutionNote string
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Type      string
+	ChirpID   uuid.NullUUID
+	GroupKey  string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	ReadAt    sql.NullTime
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+	CreatedAt      time.Time
+}
+
+type NotificationPreference struct {
+	UserID  uuid.UUID
+	Type    string
+	Enabled bool
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	CreatedAt     time.Time
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+	Scopes       []string
+}
+
+type OauthConsent struct {
+	UserID    uuid.UUID
+	ClientID  uuid.UUID
+	Scopes    []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	CreatedAt time.Time
 	UserID    uuid.UUID
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	LastUsedAt sql.NullTime
+	ExpiresAt  sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type RateLimitBucket struct {
+	BucketKey string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type RefreshToken struct {
+	Token                 string
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	UserID                uuid.UUID
+	ExpiresAt             time.Time
+	RevokedAt             sql.NullTime
+	ClientID              uuid.NullUUID
+	Scopes                []string
+	AuthorizationCodeHash sql.NullString
+}
+
+type SubscriptionEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Event     string
+}
+
+type TotpRecoveryCode struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
+}
+
+type TrendingHashtag struct {
+	TimeWindow  string
+	ComputedAt  time.Time
+	Rank        int32
+	Tag         string
+	ChirpCount  int64
+	AuthorCount int64
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Email               string
+	HashedPassword      string
+	IsChirpyRed         bool
+	TotpSecret          sql.NullString
+	TotpEnabled         bool
+	TotpLastUsedStep    int64
+	EmailVerified       bool
+	Handle              sql.NullString
+	DisplayName         string
+	Bio                 string
+	AvatarUrl           string
+	Location            string
+	DeletionScheduledAt sql.NullTime
+	Role                string
+	SuspendedAt         sql.NullTime
+	SuspendedUntil      sql.NullTime
+	SuspensionReason    string
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserMute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
 }
@@ -0,0 +1,17 @@
+package database
+
+import "strings"
+
+// QueryName extracts the sqlc query name from the "-- name: X :kind" header
+// that sqlc prepends to every generated statement.
+func QueryName(query string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(query, prefix) {
+		return "unknown"
+	}
+	fields := strings.Fields(strings.TrimPrefix(query, prefix))
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return fields[0]
+}
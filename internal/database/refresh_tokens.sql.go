@@ -11,8 +11,52 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const createOAuthRefreshToken = `-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, client_id, scopes, authorization_code_hash)
+VALUES (
+    $1, $2, $2, $3, $4, $5, $6, $7
+)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes, authorization_code_hash
+`
+
+type CreateOAuthRefreshTokenParams struct {
+	Token                 string
+	CreatedAt             time.Time
+	UserID                uuid.UUID
+	ExpiresAt             time.Time
+	ClientID              uuid.NullUUID
+	Scopes                []string
+	AuthorizationCodeHash sql.NullString
+}
+
+func (q *Queries) CreateOAuthRefreshToken(ctx context.Context, arg CreateOAuthRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthRefreshToken,
+		arg.Token,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.ClientID,
+		pq.Array(arg.Scopes),
+		arg.AuthorizationCodeHash,
+	)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+		&i.AuthorizationCodeHash,
+	)
+	return i, err
+}
+
 const createRefreshToken = `-- name: CreateRefreshToken :one
 INSERT INTO refresh_tokens(
     token,
@@ -25,7 +69,7 @@ INSERT INTO refresh_tokens(
 VALUES (
     $1, $2, $3, $4, $5, $6
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes, authorization_code_hash
 `
 
 type CreateRefreshTokenParams struct {
@@ -54,10 +98,122 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+		&i.AuthorizationCodeHash,
+	)
+	return i, err
+}
+
+const getActiveRefreshToken = `-- name: GetActiveRefreshToken :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes, authorization_code_hash FROM refresh_tokens
+WHERE token = $1
+AND revoked_at IS NULL
+AND expires_at > NOW()
+`
+
+func (q *Queries) GetActiveRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getActiveRefreshToken, token)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+		&i.AuthorizationCodeHash,
 	)
 	return i, err
 }
 
+const getRefreshTokensByUserID = `-- name: GetRefreshTokensByUserID :many
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes, authorization_code_hash FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetRefreshTokensByUserID(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, getRefreshTokensByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.Token,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.ClientID,
+			pq.Array(&i.Scopes),
+			&i.AuthorizationCodeHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeOAuthRefreshTokens = `-- name: RevokeOAuthRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE user_id = $3 AND client_id = $4 AND revoked_at IS NULL
+`
+
+type RevokeOAuthRefreshTokensParams struct {
+	RevokedAt sql.NullTime
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	ClientID  uuid.NullUUID
+}
+
+func (q *Queries) RevokeOAuthRefreshTokens(ctx context.Context, arg RevokeOAuthRefreshTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOAuthRefreshTokens,
+		arg.RevokedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.ClientID,
+	)
+	return err
+}
+
+const revokeOtherRefreshTokens = `-- name: RevokeOtherRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE user_id = $3 AND token <> $4 AND revoked_at IS NULL
+`
+
+type RevokeOtherRefreshTokensParams struct {
+	RevokedAt sql.NullTime
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Token     string
+}
+
+func (q *Queries) RevokeOtherRefreshTokens(ctx context.Context, arg RevokeOtherRefreshTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOtherRefreshTokens,
+		arg.RevokedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Token,
+	)
+	return err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = $1, updated_at = $2
@@ -74,3 +230,40 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, arg RevokeRefreshToken
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, arg.RevokedAt, arg.UpdatedAt, arg.UserID)
 	return err
 }
+
+const revokeRefreshTokenByToken = `-- name: RevokeRefreshTokenByToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE token = $3 AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokenByTokenParams struct {
+	RevokedAt sql.NullTime
+	UpdatedAt time.Time
+	Token     string
+}
+
+func (q *Queries) RevokeRefreshTokenByToken(ctx context.Context, arg RevokeRefreshTokenByTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenByToken, arg.RevokedAt, arg.UpdatedAt, arg.Token)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeRefreshTokensByAuthorizationCode = `-- name: RevokeRefreshTokensByAuthorizationCode :exec
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE authorization_code_hash = $3 AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokensByAuthorizationCodeParams struct {
+	RevokedAt             sql.NullTime
+	UpdatedAt             time.Time
+	AuthorizationCodeHash sql.NullString
+}
+
+func (q *Queries) RevokeRefreshTokensByAuthorizationCode(ctx context.Context, arg RevokeRefreshTokensByAuthorizationCodeParams) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokensByAuthorizationCode, arg.RevokedAt, arg.UpdatedAt, arg.AuthorizationCodeHash)
+	return err
+}
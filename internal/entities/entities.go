@@ -0,0 +1,128 @@
+// Package entities finds the hashtags and mentions in a chirp body.
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Type string
+
+const (
+	TypeHashtag Type = "hashtag"
+	TypeMention Type = "mention"
+)
+
+const (
+	maxHashtagLength = 100
+	minHandleLength  = 3
+	maxHandleLength  = 30
+)
+
+// Entity is one hashtag or mention. Text excludes the leading # or @, but
+// the span from Start to End covers it. Offsets count Unicode code points and
+// End is exclusive.
+type Entity struct {
+	Type  Type   `json:"type"`
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Parse returns the entities in body in order of appearance.
+//
+// A hashtag is # followed by letters, digits and underscores, at least one of
+// them a letter, so "#1" is not a tag. A mention is @ followed by a possible
+// handle. Either must start the body or follow a character that can't be part
+// of a word, so email addresses and "a#b" are left alone.
+func Parse(body string) []Entity {
+	runes := []rune(body)
+	found := []Entity{}
+	for i := 0; i < len(runes); i++ {
+		sigil := runes[i]
+		if sigil != '#' && sigil != '@' {
+			continue
+		}
+		if i > 0 && (isWordRune(runes[i-1]) || runes[i-1] == '#' || runes[i-1] == '@') {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		text := string(runes[i+1 : end])
+		entity := Entity{Text: text, Start: i, End: end}
+		switch {
+		case sigil == '#' && isHashtag(runes[i+1:end]):
+			entity.Type = TypeHashtag
+		case sigil == '@' && isHandle(runes[i+1:end]):
+			entity.Type = TypeMention
+		default:
+			continue
+		}
+		found = append(found, entity)
+		i = end - 1
+	}
+	return found
+}
+
+// Hashtags returns the distinct hashtags in body, normalized with
+// NormalizeHashtag.
+func Hashtags(body string) []string {
+	return distinct(body, TypeHashtag, NormalizeHashtag)
+}
+
+// Mentions returns the distinct handles mentioned in body, lower-cased.
+func Mentions(body string) []string {
+	return distinct(body, TypeMention, strings.ToLower)
+}
+
+// NormalizeHashtag gives the form hashtags are stored and looked up in, so
+// #Go and #go are the same tag.
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+func distinct(body string, entityType Type, normalize func(string) string) []string {
+	seen := map[string]bool{}
+	values := []string{}
+	for _, entity := range Parse(body) {
+		value := normalize(entity.Text)
+		if entity.Type != entityType || seen[value] {
+			continue
+		}
+		seen[value] = true
+		values = append(values, value)
+	}
+	return values
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isHashtag(text []rune) bool {
+	if len(text) == 0 || len(text) > maxHashtagLength {
+		return false
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHandle matches the handle rules: 3-30 ASCII letters, digits or
+// underscores.
+func isHandle(text []rune) bool {
+	if len(text) < minHandleLength || len(text) > maxHandleLength {
+		return false
+	}
+	for _, r := range text {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package entities
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Entity
+	}{
+		{name: "empty", body: "", want: []Entity{}},
+		{name: "hashtag", body: "#go", want: []Entity{
+			{Type: TypeHashtag, Text: "go", Start: 0, End: 3},
+		}},
+		{name: "mention", body: "hi @alice!", want: []Entity{
+			{Type: TypeMention, Text: "alice", Start: 3, End: 9},
+		}},
+		{name: "offsets count code points", body: "héllo 🐦 #chirpy @bob_1", want: []Entity{
+			{Type: TypeHashtag, Text: "chirpy", Start: 8, End: 15},
+			{Type: TypeMention, Text: "bob_1", Start: 16, End: 22},
+		}},
+		{name: "unicode hashtag", body: "#café", want: []Entity{
+			{Type: TypeHashtag, Text: "café", Start: 0, End: 5},
+		}},
+		{name: "hashtag needs a letter", body: "#1 #2024 #v2", want: []Entity{
+			{Type: TypeHashtag, Text: "v2", Start: 9, End: 12},
+		}},
+		{name: "email address", body: "mail me at bob@example.com", want: []Entity{}},
+		{name: "sigil inside a word", body: "a#b c@def", want: []Entity{}},
+		{name: "doubled sigils", body: "##go @@alice #@bob", want: []Entity{}},
+		{name: "after punctuation", body: "(#go),@alice.", want: []Entity{
+			{Type: TypeHashtag, Text: "go", Start: 1, End: 4},
+			{Type: TypeMention, Text: "alice", Start: 6, End: 12},
+		}},
+		{name: "handle too short", body: "@ab", want: []Entity{}},
+		{name: "handle too long", body: "@" + strings.Repeat("a", maxHandleLength+1), want: []Entity{}},
+		{name: "longest handle", body: "@" + strings.Repeat("a", maxHandleLength), want: []Entity{
+			{Type: TypeMention, Text: strings.Repeat("a", maxHandleLength), Start: 0, End: maxHandleLength + 1},
+		}},
+		{name: "handle must be ASCII", body: "@josé", want: []Entity{}},
+		{name: "hashtag too long", body: "#" + strings.Repeat("a", maxHashtagLength+1), want: []Entity{}},
+		{name: "bare sigils", body: "# @ #", want: []Entity{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashtagsAndMentions(t *testing.T) {
+	body := "#Go and #go with @Alice, @alice and #Rust"
+	if got, want := Hashtags(body), []string{"go", "rust"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Hashtags() = %v, want %v", got, want)
+	}
+	if got, want := Mentions(body), []string{"alice"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Mentions() = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "go", want: "go"},
+		{tag: "#Go", want: "go"},
+		{tag: "  #GoLang ", want: "golang"},
+		{tag: "Café", want: "café"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeHashtag(tt.tag); got != tt.want {
+			t.Errorf("NormalizeHashtag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/blob"
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	Location    string    `json:"location"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	TOTPEnabled bool      `json:"totp_enabled"`
+}
+
+type chirp struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+}
+
+// session describes a refresh token without including the token itself.
+type session struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+type personalAccessToken struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// message is one in a conversation the user is in, sent by them or to them.
+type message struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Body           string    `json:"body"`
+}
+
+// attachment describes an upload. File is where the processed image is in
+// the archive; uploads that were never processed have none.
+type attachment struct {
+	ID          uuid.UUID  `json:"id"`
+	ChirpID     *uuid.UUID `json:"chirp_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	Width       *int32     `json:"width"`
+	Height      *int32     `json:"height"`
+	File        string     `json:"file,omitempty"`
+}
+
+type attachmentFile struct {
+	name string
+	data []byte
+}
+
+type subscriptionEvent struct {
+	CreatedAt time.Time `json:"created_at"`
+	Event     string    `json:"event"`
+}
+
+// Build collects everything stored about a user into a ZIP archive of JSON
+// files, along with their processed attachments from blobs. Secrets such as
+// password hashes and token values are left out.
+func Build(ctx context.Context, db *database.Queries, blobs blob.Store, userID uuid.UUID) ([]byte, error) {
+	dbUser, err := db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dbChirps, err := db.GetChirpsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dbRefreshTokens, err := db.GetRefreshTokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dbPersonalAccessTokens, err := db.GetPersonalAccessTokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dbSubscriptionEvents, err := db.GetSubscriptionEventsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dbMessages, err := db.GetMessagesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dbAttachments, err := db.GetAttachmentsByUserID(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	chirps := make([]chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirp{
+			ID:        dbChirp.ID,
+			CreatedAt: dbChirp.CreatedAt,
+			UpdatedAt: dbChirp.UpdatedAt,
+			Body:      dbChirp.Body,
+		}
+	}
+	sessions := make([]session, len(dbRefreshTokens))
+	for i, dbRefreshToken := range dbRefreshTokens {
+		sessions[i] = session{
+			CreatedAt: dbRefreshToken.CreatedAt,
+			ExpiresAt: dbRefreshToken.ExpiresAt,
+		}
+		if dbRefreshToken.RevokedAt.Valid {
+			sessions[i].RevokedAt = &dbRefreshToken.RevokedAt.Time
+		}
+	}
+	personalAccessTokens := make([]personalAccessToken, len(dbPersonalAccessTokens))
+	for i, dbToken := range dbPersonalAccessTokens {
+		personalAccessTokens[i] = personalAccessToken{
+			CreatedAt: dbToken.CreatedAt,
+			Name:      dbToken.Name,
+			Scopes:    dbToken.Scopes,
+		}
+		if dbToken.LastUsedAt.Valid {
+			personalAccessTokens[i].LastUsedAt = &dbToken.LastUsedAt.Time
+		}
+		if dbToken.ExpiresAt.Valid {
+			personalAccessTokens[i].ExpiresAt = &dbToken.ExpiresAt.Time
+		}
+	}
+	subscriptionEvents := make([]subscriptionEvent, len(dbSubscriptionEvents))
+	for i, dbEvent := range dbSubscriptionEvents {
+		subscriptionEvents[i] = subscriptionEvent{
+			CreatedAt: dbEvent.CreatedAt,
+			Event:     dbEvent.Event,
+		}
+	}
+
+	messages := make([]message, len(dbMessages))
+	for i, dbMessage := range dbMessages {
+		messages[i] = message{
+			ID:             dbMessage.ID,
+			ConversationID: dbMessage.ConversationID,
+			SenderID:       dbMessage.SenderID,
+			CreatedAt:      dbMessage.CreatedAt,
+			Body:           dbMessage.Body,
+		}
+	}
+
+	attachments := make([]attachment, len(dbAttachments))
+	var attachmentFiles []attachmentFile
+	for i, dbAttachment := range dbAttachments {
+		attachments[i] = attachment{
+			ID:          dbAttachment.ID,
+			CreatedAt:   dbAttachment.CreatedAt,
+			Status:      dbAttachment.Status,
+			ContentType: dbAttachment.ContentType,
+			Size:        dbAttachment.Size,
+		}
+		if dbAttachment.ChirpID.Valid {
+			attachments[i].ChirpID = &dbAttachment.ChirpID.UUID
+		}
+		if dbAttachment.Width.Valid && dbAttachment.Height.Valid {
+			attachments[i].Width = &dbAttachment.Width.Int32
+			attachments[i].Height = &dbAttachment.Height.Int32
+		}
+		// Only processed images: raw uploads still carry their metadata and
+		// are on their way to being processed or purged.
+		if dbAttachment.Status != "ready" {
+			continue
+		}
+		data, err := readBlob(ctx, blobs, dbAttachment.StorageKey)
+		if errors.Is(err, blob.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := "attachments/" + dbAttachment.ID.String() + path.Ext(dbAttachment.StorageKey)
+		attachments[i].File = name
+		attachmentFiles = append(attachmentFiles, attachmentFile{name: name, data: data})
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile{
+			ID:          dbUser.ID,
+			CreatedAt:   dbUser.CreatedAt,
+			UpdatedAt:   dbUser.UpdatedAt,
+			Email:       dbUser.Email,
+			Handle:      dbUser.Handle.String,
+			DisplayName: dbUser.DisplayName,
+			Bio:         dbUser.Bio,
+			AvatarURL:   dbUser.AvatarUrl,
+			Location:    dbUser.Location,
+			IsChirpyRed: dbUser.IsChirpyRed,
+			TOTPEnabled: dbUser.TotpEnabled,
+		}},
+		{"chirps.json", chirps},
+		{"sessions.json", sessions},
+		{"personal_access_tokens.json", personalAccessTokens},
+		{"subscription_history.json", subscriptionEvents},
+		{"messages.json", messages},
+		{"attachments.json", attachments},
+	}
+
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(file.data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range attachmentFiles {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write(file.data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func readBlob(ctx context.Context, blobs blob.Store, key string) ([]byte, error) {
+	body, err := blobs.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
@@ -0,0 +1,88 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image/gif"
+)
+
+var errInvalidGIF = errors.New("invalid GIF")
+
+// RebuildGIF decodes every frame of a GIF and encodes them again with their
+// timing, disposal and loop count, and nothing else: comments and
+// application extensions, such as XMP packets that may hold a GPS position,
+// are left behind. Call CheckSize first; it counts every frame.
+func RebuildGIF(data []byte) ([]byte, error) {
+	decoded, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = gif.EncodeAll(&buf, &gif.GIF{
+		Image:           decoded.Image,
+		Delay:           decoded.Delay,
+		LoopCount:       decoded.LoopCount,
+		Disposal:        decoded.Disposal,
+		Config:          decoded.Config,
+		BackgroundIndex: decoded.BackgroundIndex,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gifPixels adds up the pixels of every frame of a GIF by walking its blocks,
+// without decompressing any of them. A few kilobytes of LZW data can claim a
+// great many frames, each up to the size of the whole image.
+func gifPixels(data []byte) (int64, error) {
+	const (
+		headerSize          = 6
+		screenDescriptorEnd = headerSize + 7
+		colorTableFlag      = 0x80
+	)
+	if len(data) < screenDescriptorEnd {
+		return 0, errInvalidGIF
+	}
+	i := screenDescriptorEnd
+	if flags := data[headerSize+4]; flags&colorTableFlag != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+	var pixels int64
+	for i < len(data) {
+		switch data[i] {
+		case 0x3B: // Trailer.
+			return pixels, nil
+		case 0x21: // Extension: label, then sub-blocks.
+			i += 2
+		case 0x2C: // Image descriptor, then LZW minimum code size and sub-blocks.
+			if i+10 > len(data) {
+				return 0, errInvalidGIF
+			}
+			width := int64(data[i+5]) | int64(data[i+6])<<8
+			height := int64(data[i+7]) | int64(data[i+8])<<8
+			pixels += width * height
+			flags := data[i+9]
+			i += 10
+			if flags&colorTableFlag != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			i++
+		default:
+			return 0, errInvalidGIF
+		}
+		// Skip the sub-blocks, up to the empty one that ends them.
+		for {
+			if i >= len(data) {
+				return 0, errInvalidGIF
+			}
+			size := int(data[i])
+			i += 1 + size
+			if size == 0 {
+				break
+			}
+		}
+	}
+	// Some encoders leave out the trailer; the decoder doesn't mind either.
+	return pixels, nil
+}
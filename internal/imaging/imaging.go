@@ -0,0 +1,125 @@
+// Package imaging decodes uploaded images and derives what chirps show of
+// them: metadata-free re-encodings, thumbnails and blurhash placeholders.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatGIF  Format = "gif"
+	FormatWebP Format = "webp"
+)
+
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+func (f Format) Extension() string {
+	if f == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + string(f)
+}
+
+// ErrTooManyPixels rejects decompression bombs: small files that claim
+// dimensions which would take far more memory than the file to decode.
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// CheckSize reads just the header of data and fails with ErrTooManyPixels if
+// decoding it would mean more than maxPixels pixels. The frames of a GIF are
+// counted together, since RebuildGIF decodes them all.
+func CheckSize(data []byte, maxPixels int) (image.Config, Format, error) {
+	config, name, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, "", err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return image.Config{}, "", fmt.Errorf("invalid dimensions %dx%d", config.Width, config.Height)
+	}
+	pixels := int64(config.Width) * int64(config.Height)
+	if Format(name) == FormatGIF {
+		pixels, err = gifPixels(data)
+		if err != nil {
+			return image.Config{}, "", err
+		}
+	}
+	if pixels > int64(maxPixels) {
+		return image.Config{}, "", ErrTooManyPixels
+	}
+	return config, Format(name), nil
+}
+
+// Decode decodes the first frame of data after CheckSize, and turns JPEGs
+// upright according to their EXIF orientation, which is lost on re-encoding.
+func Decode(data []byte, maxPixels int) (image.Image, Format, error) {
+	_, format, err := CheckSize(data, maxPixels)
+	if err != nil {
+		return nil, "", err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if format == FormatJPEG {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, format, nil
+}
+
+// Opaque reports whether img has no transparent pixels, which JPEG can't
+// store.
+func Opaque(img image.Image) bool {
+	opaque, ok := img.(interface{ Opaque() bool })
+	return ok && opaque.Opaque()
+}
+
+// Encode writes img as format. Only the pixels are written: nothing of the
+// metadata of the file img was decoded from survives. WebP can't be encoded,
+// so callers pick JPEG or PNG instead.
+func Encode(w io.Writer, img image.Image, format Format) error {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatGIF:
+		return gif.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("cannot encode %s", format)
+	}
+}
+
+// Fit scales img down, keeping its aspect ratio, so that neither side is
+// longer than maxSize. Images that already fit are returned as they are.
+func Fit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		return img
+	}
+	if width >= height {
+		height = max(1, height*maxSize/width)
+		width = maxSize
+	} else {
+		width = max(1, width*maxSize/height)
+		height = maxSize
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,309 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func solid(width, height int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation returns a JPEG of img with an EXIF segment holding just
+// the given orientation, big- or little-endian.
+func withOrientation(t *testing.T, img image.Image, orientation uint16, order binary.ByteOrder) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func TestCheckSize(t *testing.T) {
+	data := encodePNG(t, solid(100, 50, color.White))
+	tests := []struct {
+		name      string
+		data      []byte
+		maxPixels int
+		wantErr   error
+	}{
+		{name: "fits", data: data, maxPixels: 5000},
+		{name: "one pixel too many", data: data, maxPixels: 4999, wantErr: ErrTooManyPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, format, err := CheckSize(tt.data, tt.maxPixels)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckSize() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if config.Width != 100 || config.Height != 50 || format != FormatPNG {
+				t.Errorf("CheckSize() = %dx%d %s, want 100x50 png", config.Width, config.Height, format)
+			}
+		})
+	}
+
+	if _, _, err := CheckSize([]byte("not an image"), 5000); err == nil {
+		t.Error("CheckSize() of garbage succeeded")
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height         int
+		maxSize               int
+		wantWidth, wantHeight int
+	}{
+		{name: "already fits", width: 100, height: 50, maxSize: 100, wantWidth: 100, wantHeight: 50},
+		{name: "landscape", width: 400, height: 200, maxSize: 100, wantWidth: 100, wantHeight: 50},
+		{name: "portrait", width: 200, height: 400, maxSize: 100, wantWidth: 50, wantHeight: 100},
+		{name: "square", width: 300, height: 300, maxSize: 32, wantWidth: 32, wantHeight: 32},
+		{name: "sliver keeps a pixel", width: 1000, height: 2, maxSize: 100, wantWidth: 100, wantHeight: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := solid(tt.width, tt.height, color.White)
+			got := Fit(img, tt.maxSize).Bounds()
+			if got.Dx() != tt.wantWidth || got.Dy() != tt.wantHeight {
+				t.Errorf("Fit(%dx%d, %d) = %dx%d, want %dx%d", tt.width, tt.height, tt.maxSize, got.Dx(), got.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func decodeBase83(s string) int {
+	value := 0
+	for _, c := range s {
+		value = value*83 + strings.IndexRune(blurhashCharacters, c)
+	}
+	return value
+}
+
+func TestBlurhash(t *testing.T) {
+	tests := []struct {
+		name                     string
+		color                    color.NRGBA
+		xComponents, yComponents int
+	}{
+		{name: "red 4x3", color: color.NRGBA{R: 255, A: 255}, xComponents: 4, yComponents: 3},
+		{name: "grey 1x1", color: color.NRGBA{R: 128, G: 128, B: 128, A: 255}, xComponents: 1, yComponents: 1},
+		{name: "teal 9x9", color: color.NRGBA{G: 128, B: 128, A: 255}, xComponents: 9, yComponents: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := Blurhash(solid(64, 48, tt.color), tt.xComponents, tt.yComponents)
+			components := tt.xComponents * tt.yComponents
+			if len(hash) != 4+2*components {
+				t.Fatalf("Blurhash() = %q, want %d characters", hash, 4+2*components)
+			}
+			if got, want := decodeBase83(hash[:1]), (tt.xComponents-1)+(tt.yComponents-1)*9; got != want {
+				t.Errorf("size flag = %d, want %d", got, want)
+			}
+			// The DC component of a single-colour image is that colour.
+			want := int(tt.color.R)<<16 | int(tt.color.G)<<8 | int(tt.color.B)
+			if got := decodeBase83(hash[2:6]); got != want {
+				t.Errorf("DC = %06x, want %06x", got, want)
+			}
+		})
+	}
+
+	gradient := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			gradient.Set(x, y, color.NRGBA{R: uint8(x * 4), B: uint8(y * 4), A: 255})
+		}
+	}
+	if Blurhash(gradient, 4, 3) == Blurhash(solid(64, 64, color.Black), 4, 3) {
+		t.Error("Blurhash() of a gradient matches that of a solid image")
+	}
+}
+
+func TestOrientation(t *testing.T) {
+	// 20x10, left half black, right half white.
+	img := solid(20, 10, color.White)
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			img.Set(x, y, color.Black)
+		}
+	}
+	tests := []struct {
+		name           string
+		orientation    uint16
+		order          binary.ByteOrder
+		wantWidth      int
+		wantHeight     int
+		wantDarkCorner image.Point
+	}{
+		{name: "upright", orientation: 1, order: binary.BigEndian, wantWidth: 20, wantHeight: 10, wantDarkCorner: image.Pt(0, 0)},
+		{name: "mirrored", orientation: 2, order: binary.LittleEndian, wantWidth: 20, wantHeight: 10, wantDarkCorner: image.Pt(19, 0)},
+		{name: "upside down", orientation: 3, order: binary.BigEndian, wantWidth: 20, wantHeight: 10, wantDarkCorner: image.Pt(19, 9)},
+		{name: "rotated clockwise", orientation: 6, order: binary.LittleEndian, wantWidth: 10, wantHeight: 20, wantDarkCorner: image.Pt(9, 0)},
+		{name: "rotated anticlockwise", orientation: 8, order: binary.BigEndian, wantWidth: 10, wantHeight: 20, wantDarkCorner: image.Pt(0, 19)},
+		{name: "out of range is ignored", orientation: 9, order: binary.BigEndian, wantWidth: 20, wantHeight: 10, wantDarkCorner: image.Pt(0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := withOrientation(t, img, tt.orientation, tt.order)
+			decoded, format, err := Decode(data, 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != FormatJPEG {
+				t.Errorf("format = %s, want jpeg", format)
+			}
+			bounds := decoded.Bounds()
+			if bounds.Dx() != tt.wantWidth || bounds.Dy() != tt.wantHeight {
+				t.Fatalf("decoded %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+			r, _, _, _ := decoded.At(bounds.Min.X+tt.wantDarkCorner.X, bounds.Min.Y+tt.wantDarkCorner.Y).RGBA()
+			if r>>8 > 64 {
+				t.Errorf("pixel at %v is light, want dark", tt.wantDarkCorner)
+			}
+		})
+	}
+
+	if got := jpegOrientation([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}); got != 1 {
+		t.Errorf("jpegOrientation() of a truncated segment = %d, want 1", got)
+	}
+}
+
+// animatedGIF returns a GIF of frames solid frames of 20x10, with an XMP
+// packet and a comment holding secret before its trailer.
+func animatedGIF(t *testing.T, frames int, secret string) []byte {
+	t.Helper()
+	anim := &gif.GIF{LoopCount: 0}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 20, 10), palette.Plan9)
+		for p := range frame.Pix {
+			frame.Pix[p] = uint8(i * 10)
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10*(i+1))
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	var extensions []byte
+	extensions = append(extensions, 0x21, 0xFF, 11)
+	extensions = append(extensions, "XMP DataXMP"...)
+	extensions = append(extensions, byte(len(secret)))
+	extensions = append(extensions, secret...)
+	extensions = append(extensions, 0)
+	extensions = append(extensions, 0x21, 0xFE, byte(len(secret)))
+	extensions = append(extensions, secret...)
+	extensions = append(extensions, 0)
+
+	out := append([]byte{}, data[:len(data)-1]...)
+	out = append(out, extensions...)
+	return append(out, data[len(data)-1])
+}
+
+func TestRebuildGIF(t *testing.T) {
+	const secret = "<exif:GPSLatitude>50,27.0N</exif:GPSLatitude>"
+	data := animatedGIF(t, 3, secret)
+	if !bytes.Contains(data, []byte(secret)) {
+		t.Fatal("test GIF lacks its metadata")
+	}
+
+	rebuilt, err := RebuildGIF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(rebuilt, []byte(secret)) || bytes.Contains(rebuilt, []byte("XMP DataXMP")) {
+		t.Error("RebuildGIF() kept the metadata")
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(rebuilt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Image) != 3 {
+		t.Fatalf("rebuilt GIF has %d frames, want 3", len(decoded.Image))
+	}
+	for i, delay := range decoded.Delay {
+		if delay != 10*(i+1) {
+			t.Errorf("frame %d delay = %d, want %d", i, delay, 10*(i+1))
+		}
+	}
+}
+
+func TestCheckSizeCountsGIFFrames(t *testing.T) {
+	data := animatedGIF(t, 3, "comment")
+	tests := []struct {
+		name      string
+		maxPixels int
+		wantErr   error
+	}{
+		{name: "all frames fit", maxPixels: 3 * 200},
+		{name: "one frame fits", maxPixels: 3*200 - 1, wantErr: ErrTooManyPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, format, err := CheckSize(data, tt.maxPixels)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckSize() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && format != FormatGIF {
+				t.Errorf("format = %s, want gif", format)
+			}
+		})
+	}
+
+	if _, _, err := CheckSize(data[:len(data)/2], 1000); err == nil {
+		t.Error("CheckSize() of a truncated GIF succeeded")
+	}
+}
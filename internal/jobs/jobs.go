@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Every runs fn in the background each interval until ctx is cancelled.
+// Failures are logged and the job simply runs again on the next tick.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := fn(ctx)
+				if err != nil {
+					slog.ErrorContext(ctx, "Job failed", "job", name, "error", err)
+				}
+			}
+		}
+	}()
+}
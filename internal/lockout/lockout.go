@@ -0,0 +1,155 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Policy describes how failed logins for one subject are throttled: after
+// DelayAfter failures each further attempt must wait an exponentially growing
+// delay, and after LockAfter failures the subject is locked out. Counters
+// start over once no failure has been seen for ResetAfter.
+type Policy struct {
+	DelayAfter   int32
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockAfter    int32
+	LockDuration time.Duration
+	ResetAfter   time.Duration
+}
+
+var (
+	AccountPolicy = Policy{
+		DelayAfter:   3,
+		BaseDelay:    time.Second,
+		MaxDelay:     30 * time.Second,
+		LockAfter:    10,
+		LockDuration: 15 * time.Minute,
+		ResetAfter:   time.Hour,
+	}
+	// IPPolicy is looser than AccountPolicy since many users can share an
+	// address behind NAT.
+	IPPolicy = Policy{
+		DelayAfter:   20,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		LockAfter:    100,
+		LockDuration: 15 * time.Minute,
+		ResetAfter:   time.Hour,
+	}
+)
+
+func (p Policy) blockFor(failures int32) time.Duration {
+	if failures >= p.LockAfter {
+		return p.LockDuration
+	}
+	if failures < p.DelayAfter {
+		return 0
+	}
+	delay := p.BaseDelay << (failures - p.DelayAfter)
+	if delay <= 0 || delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+type Guard struct {
+	db            *database.Queries
+	accountPolicy Policy
+	ipPolicy      Policy
+}
+
+func NewGuard(db *database.Queries, accountPolicy, ipPolicy Policy) *Guard {
+	return &Guard{
+		db:            db,
+		accountPolicy: accountPolicy,
+		ipPolicy:      ipPolicy,
+	}
+}
+
+// Check returns how long the caller has to wait before trying to log in again
+// as email from ip, or zero if an attempt is allowed now. Accounts are keyed by
+// the submitted email rather than the user ID so unknown emails are throttled
+// exactly like real ones.
+func (g *Guard) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	now := time.Now().UTC()
+	var wait time.Duration
+	for _, subject := range []string{accountSubject(email), ipSubject(ip)} {
+		failure, err := g.db.GetLoginFailure(ctx, subject)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if failure.BlockedUntil.Valid && failure.BlockedUntil.Time.After(now) {
+			wait = max(wait, failure.BlockedUntil.Time.Sub(now))
+		}
+	}
+	return wait, nil
+}
+
+func (g *Guard) RecordFailure(ctx context.Context, email, ip string) error {
+	err := g.recordFailure(ctx, accountSubject(email), ip, g.accountPolicy)
+	if err != nil {
+		return err
+	}
+	return g.recordFailure(ctx, ipSubject(ip), ip, g.ipPolicy)
+}
+
+func (g *Guard) RecordSuccess(ctx context.Context, email string) error {
+	return g.db.DeleteLoginFailures(ctx, accountSubject(email))
+}
+
+func (g *Guard) recordFailure(ctx context.Context, subject, ip string, policy Policy) error {
+	now := time.Now().UTC()
+	failure, err := g.db.IncrementLoginFailures(ctx, database.IncrementLoginFailuresParams{
+		Subject:     subject,
+		FailedAt:    now,
+		ResetBefore: now.Add(-policy.ResetAfter),
+	})
+	if err != nil {
+		return err
+	}
+
+	blockFor := policy.blockFor(failure.Failures)
+	if blockFor == 0 {
+		return nil
+	}
+	blockedUntil := now.Add(blockFor)
+	err = g.db.SetLoginFailureBlockedUntil(ctx, database.SetLoginFailureBlockedUntilParams{
+		BlockedUntil: sql.NullTime{Time: blockedUntil, Valid: true},
+		Subject:      subject,
+	})
+	if err != nil {
+		return err
+	}
+
+	if failure.Failures < policy.LockAfter {
+		return nil
+	}
+	slog.WarnContext(ctx, "Login locked out", "subject", subject, "ip", ip, "failures", failure.Failures, "locked_until", blockedUntil)
+	return g.db.CreateLoginLockout(ctx, database.CreateLoginLockoutParams{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		Subject:     subject,
+		IpAddress:   ip,
+		Failures:    failure.Failures,
+		LockedUntil: blockedUntil,
+	})
+}
+
+func accountSubject(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
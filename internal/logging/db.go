@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+)
+
+type loggedDB struct {
+	db database.DBTX
+}
+
+// LogDB wraps the connection handed to database.New so failed queries are
+// logged with the request ID of the context they ran under.
+func LogDB(db database.DBTX) database.DBTX {
+	return &loggedDB{db: db}
+}
+
+func (l *loggedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := l.db.ExecContext(ctx, query, args...)
+	logQueryError(ctx, query, err)
+	return res, err
+}
+
+func (l *loggedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := l.db.PrepareContext(ctx, query)
+	logQueryError(ctx, query, err)
+	return stmt, err
+}
+
+func (l *loggedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	logQueryError(ctx, query, err)
+	return rows, err
+}
+
+func (l *loggedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := l.db.QueryRowContext(ctx, query, args...)
+	logQueryError(ctx, query, row.Err())
+	return row
+}
+
+func logQueryError(ctx context.Context, query string, err error) {
+	if err == nil {
+		return
+	}
+	slog.ErrorContext(ctx, "database query failed", "query", database.QueryName(query), "error", err)
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Type string
+
+const (
+	TypeLog  Type = "log"
+	TypeFile Type = "file"
+	TypeSMTP Type = "smtp"
+)
+
+// FromEnv builds the mailer selected by MAILER. SMTP is configured with
+// SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD and MAIL_FROM; the file
+// sink writes to MAIL_DIR.
+func FromEnv() (Mailer, error) {
+	switch Type(os.Getenv("MAILER")) {
+	case "", TypeLog:
+		return NewLogMailer(), nil
+	case TypeFile:
+		return NewFileMailer(os.Getenv("MAIL_DIR"))
+	case TypeSMTP:
+		return NewSMTPMailer(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown mailer: %s", os.Getenv("MAILER"))
+	}
+}
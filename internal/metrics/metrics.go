@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "chirpy"
+
+type LoginResult string
+
+const (
+	LoginResultSuccess       LoginResult = "success"
+	LoginResultUnknownUser   LoginResult = "unknown_user"
+	LoginResultWrongPassword LoginResult = "wrong_password"
+	LoginResultBlocked       LoginResult = "blocked"
+	LoginResultMFARequired   LoginResult = "mfa_required"
+	LoginResultWrongMFACode  LoginResult = "wrong_mfa_code"
+	LoginResultError         LoginResult = "error"
+)
+
+type WebhookOutcome string
+
+const (
+	WebhookOutcomeUnauthorized WebhookOutcome = "unauthorized"
+	WebhookOutcomeBadRequest   WebhookOutcome = "bad_request"
+	WebhookOutcomeIgnored      WebhookOutcome = "ignored"
+	WebhookOutcomeProcessed    WebhookOutcome = "processed"
+	WebhookOutcomeUserNotFound WebhookOutcome = "user_not_found"
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	dbQueryDuration     *prometheus.HistogramVec
+	loginAttempts       *prometheus.CounterVec
+	webhookEvents       *prometheus.CounterVec
+	rateLimited         *prometheus.CounterVec
+}
+
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database query latency by sqlc query name.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"query", "status"}),
+		loginAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "login_attempts_total",
+			Help:      "Number of login attempts by result.",
+		}, []string{"result"}),
+		webhookEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_events_total",
+			Help:      "Number of Polka webhook calls by event and outcome.",
+		}, []string{"event", "outcome"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_requests_total",
+			Help:      "Number of requests rejected by a rate limit policy.",
+		}, []string{"policy"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, namespace),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.dbQueryDuration,
+		m.loginAttempts,
+		m.webhookEvents,
+		m.rateLimited,
+	)
+	return m
+}
+
+// RegisterGaugeFunc exposes a value owned elsewhere, such as the fileserver hit counter.
+func (m *Metrics) RegisterGaugeFunc(name, help string, fn func() float64) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveLogin(result LoginResult) {
+	m.loginAttempts.WithLabelValues(string(result)).Inc()
+}
+
+func (m *Metrics) ObserveWebhook(event string, outcome WebhookOutcome) {
+	m.webhookEvents.WithLabelValues(event, string(outcome)).Inc()
+}
+
+func (m *Metrics) ObserveRateLimited(policy string) {
+	m.rateLimited.WithLabelValues(policy).Inc()
+}
@@ -0,0 +1,65 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachChecker reports whether a password is known from a data breach.
+type BreachChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+const prefixLength = 5
+
+// DirChecker looks passwords up in an offline copy of a k-anonymity range
+// dataset such as Have I Been Pwned's: one file per five-character SHA-1
+// prefix, named like 21BD1.txt, holding lines of "SUFFIX:COUNT". Only the
+// file for the password's prefix is ever read.
+type DirChecker struct {
+	dir string
+}
+
+func NewDirChecker(dir string) (*DirChecker, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached passwords dataset is not a directory: %s", dir)
+	}
+	return &DirChecker{dir: dir}, nil
+}
+
+func (c *DirChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	f, err := os.Open(filepath.Join(c.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		// A range missing from a partial dataset has no known breaches.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(entry, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
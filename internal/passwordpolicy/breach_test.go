@@ -0,0 +1,106 @@
+package passwordpolicy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// SHA-1 of "password", split the way the range dataset is.
+const (
+	passwordPrefix = "5BAA6"
+	passwordSuffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+func TestDirChecker(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  bool
+	}{
+		{
+			name: "breached",
+			files: map[string]string{
+				passwordPrefix + ".txt": "003D68EB55068C33ACE09247EE4C639306B:3\n" + passwordSuffix + ":9545824\n",
+			},
+			want: true,
+		},
+		{
+			name:  "lowercase suffix",
+			files: map[string]string{passwordPrefix + ".txt": strings.ToLower(passwordSuffix) + ":2\n"},
+			want:  true,
+		},
+		{
+			name:  "CRLF line endings",
+			files: map[string]string{passwordPrefix + ".txt": "003D68EB55068C33ACE09247EE4C639306B:3\r\n" + passwordSuffix + ":1\r\n"},
+			want:  true,
+		},
+		{
+			name:  "padding entry",
+			files: map[string]string{passwordPrefix + ".txt": passwordSuffix + ":0\n"},
+			want:  false,
+		},
+		{
+			name:  "other suffixes only",
+			files: map[string]string{passwordPrefix + ".txt": "003D68EB55068C33ACE09247EE4C639306B:3\n"},
+			want:  false,
+		},
+		{
+			name:  "suffix under another prefix",
+			files: map[string]string{"5BAA7.txt": passwordSuffix + ":3\n"},
+			want:  false,
+		},
+		{name: "range missing", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			checker, err := NewDirChecker(dir)
+			if err != nil {
+				t.Fatalf("NewDirChecker() error = %v", err)
+			}
+			got, err := checker.IsBreached(context.Background(), "password")
+			if err != nil {
+				t.Fatalf("IsBreached() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsBreached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDirChecker(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "dataset.txt")
+	err := os.WriteFile(file, nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr bool
+	}{
+		{name: "directory", dir: dir},
+		{name: "missing", dir: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "file", dir: file, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDirChecker(tt.dir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewDirChecker(%q) error = %v, want error %v", tt.dir, err, tt.wantErr)
+			}
+		})
+	}
+}
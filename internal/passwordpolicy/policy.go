@@ -0,0 +1,141 @@
+package passwordpolicy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultMinLength = 8
+	DefaultMaxLength = 64
+)
+
+// Violation is one reason a password was rejected. Code is stable for
+// clients to match on; Message is meant for people.
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	CodeTooShort    = "too_short"
+	CodeTooLong     = "too_long"
+	CodeEqualsEmail = "equals_email"
+	CodeBreached    = "breached"
+)
+
+// ValidationError lists every rule a password broke.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Policy decides which passwords are acceptable. Lengths count characters,
+// not bytes; MaxBytes, if set, also caps the UTF-8 encoding, for hashes such
+// as bcrypt that only look at so many bytes. Breached may be nil to skip the
+// breached-password check.
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	MaxBytes      int
+	DisallowEmail bool
+	Breached      BreachChecker
+}
+
+// FromEnv builds a policy from PASSWORD_MIN_LENGTH and PASSWORD_MAX_LENGTH.
+// Breached passwords are checked against BREACHED_PASSWORDS_DIR if it is set.
+func FromEnv() (*Policy, error) {
+	minLength, err := intFromEnv("PASSWORD_MIN_LENGTH", DefaultMinLength)
+	if err != nil {
+		return nil, err
+	}
+	maxLength, err := intFromEnv("PASSWORD_MAX_LENGTH", DefaultMaxLength)
+	if err != nil {
+		return nil, err
+	}
+	if minLength > maxLength {
+		return nil, fmt.Errorf("PASSWORD_MIN_LENGTH %d exceeds PASSWORD_MAX_LENGTH %d", minLength, maxLength)
+	}
+	policy := &Policy{
+		MinLength:     minLength,
+		MaxLength:     maxLength,
+		DisallowEmail: true,
+	}
+	if dir := os.Getenv("BREACHED_PASSWORDS_DIR"); dir != "" {
+		policy.Breached, err = NewDirChecker(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+func intFromEnv(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid %s: %q", key, value)
+	}
+	return parsed, nil
+}
+
+// Check returns a *ValidationError if password breaks the policy for the
+// account with the given email. Any other error means the check itself
+// failed.
+func (p *Policy) Check(ctx context.Context, password, email string) error {
+	violations := []Violation{}
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{
+			Code:    CodeTooShort,
+			Message: fmt.Sprintf("Password must be at least %d characters", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{
+			Code:    CodeTooLong,
+			Message: fmt.Sprintf("Password must be at most %d characters", p.MaxLength),
+		})
+	} else if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violations = append(violations, Violation{
+			Code:    CodeTooLong,
+			Message: fmt.Sprintf("Password must be at most %d bytes; accented letters and symbols take several", p.MaxBytes),
+		})
+	}
+	if p.DisallowEmail && email != "" && strings.EqualFold(strings.TrimSpace(password), strings.TrimSpace(email)) {
+		violations = append(violations, Violation{
+			Code:    CodeEqualsEmail,
+			Message: "Password must not be your email address",
+		})
+	}
+	if p.Breached != nil && len(violations) == 0 {
+		breached, err := p.Breached.IsBreached(ctx, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, Violation{
+				Code:    CodeBreached,
+				Message: "Password has appeared in a data breach; choose another",
+			})
+		}
+	}
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
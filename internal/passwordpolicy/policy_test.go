@@ -0,0 +1,117 @@
+package passwordpolicy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// memoryChecker is a breach dataset held in memory.
+type memoryChecker struct {
+	breached map[string]bool
+	err      error
+	calls    int
+}
+
+func (c *memoryChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	c.calls++
+	return c.breached[password], c.err
+}
+
+func violationCodes(err error) []string {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+	codes := []string{}
+	for _, v := range validationErr.Violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestCheck(t *testing.T) {
+	base := Policy{MinLength: 8, MaxLength: 64, DisallowEmail: true}
+	bcrypt := base
+	bcrypt.MaxBytes = 72
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		email    string
+		want     []string
+	}{
+		{name: "acceptable", policy: base, password: "correct horse battery", email: "bob@example.com"},
+		{name: "too short", policy: base, password: "hunter2", want: []string{CodeTooShort}},
+		{name: "shortest", policy: base, password: "hunter22"},
+		{name: "counts characters, not bytes", policy: base, password: strings.Repeat("é", 7), want: []string{CodeTooShort}},
+		{name: "multibyte at the minimum", policy: base, password: strings.Repeat("é", 8)},
+		{name: "longest", policy: base, password: strings.Repeat("a", 64)},
+		{name: "too long", policy: base, password: strings.Repeat("a", 65), want: []string{CodeTooLong}},
+		{name: "no maximum", policy: Policy{MinLength: 8}, password: strings.Repeat("a", 1000)},
+		{name: "bytes ignored without MaxBytes", policy: base, password: strings.Repeat("é", 40)},
+		{name: "too many bytes", policy: bcrypt, password: strings.Repeat("é", 40), want: []string{CodeTooLong}},
+		{name: "most bytes", policy: bcrypt, password: strings.Repeat("é", 36)},
+		{name: "too long counted once", policy: bcrypt, password: strings.Repeat("é", 65), want: []string{CodeTooLong}},
+		{name: "equals email", policy: base, password: " Bob@Example.com", email: "bob@example.com", want: []string{CodeEqualsEmail}},
+		{name: "contains email", policy: base, password: "bob@example.com1", email: "bob@example.com"},
+		{name: "email allowed", policy: Policy{MinLength: 8}, password: "bob@example.com", email: "bob@example.com"},
+		{name: "no email", policy: base, password: "hunter22", email: ""},
+		{name: "every violation", policy: base, password: "a@b.co", email: "a@b.co", want: []string{CodeTooShort, CodeEqualsEmail}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(context.Background(), tt.password, tt.email)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			if got := violationCodes(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() violations = %v, want %v (error %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCheckBreached(t *testing.T) {
+	errDataset := errors.New("dataset unavailable")
+
+	tests := []struct {
+		name      string
+		password  string
+		checkErr  error
+		want      []string
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "not breached", password: "correct horse battery", wantCalls: 1},
+		{name: "breached", password: "password1", want: []string{CodeBreached}, wantCalls: 1},
+		{name: "skipped after another violation", password: "hunter2", want: []string{CodeTooShort}, wantCalls: 0},
+		{name: "lookup error", password: "correct horse battery", checkErr: errDataset, wantErr: errDataset, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &memoryChecker{
+				breached: map[string]bool{"password1": true, "hunter2": true},
+				err:      tt.checkErr,
+			}
+			policy := Policy{MinLength: 8, MaxLength: 64, Breached: checker}
+			err := policy.Check(context.Background(), tt.password, "")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Check() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if got := violationCodes(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() violations = %v, want %v (error %v)", got, tt.want, err)
+			}
+			if checker.calls != tt.wantCalls {
+				t.Errorf("IsBreached called %d times, want %d", checker.calls, tt.wantCalls)
+			}
+		})
+	}
+}
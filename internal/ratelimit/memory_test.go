@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestMemoryStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = clock.Now
+	s.lastSweep = clock.now
+	return s, clock
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	ctx := context.Background()
+	policy := Policy{Name: "test", Limit: 3, Window: 30 * time.Second}
+	s, clock := newTestMemoryStore()
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		key     string
+		allowed bool
+	}{
+		{name: "first", key: "a", allowed: true},
+		{name: "second", key: "a", allowed: true},
+		{name: "third", key: "a", allowed: true},
+		{name: "burst spent", key: "a", allowed: false},
+		{name: "other key", key: "b", allowed: true},
+		{name: "not refilled yet", advance: 9 * time.Second, key: "a", allowed: false},
+		{name: "one token refilled", advance: time.Second, key: "a", allowed: true},
+		{name: "spent again", key: "a", allowed: false},
+		{name: "window elapsed", advance: 30 * time.Second, key: "a", allowed: true},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		res, err := s.Take(ctx, step.key, policy)
+		if err != nil {
+			t.Fatalf("%s: Take() error = %v", step.name, err)
+		}
+		if res.Allowed != step.allowed {
+			t.Errorf("%s: Allowed = %v, want %v", step.name, res.Allowed, step.allowed)
+		}
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	short := Policy{Name: "short", Limit: 1, Window: time.Minute}
+	long := Policy{Name: "long", Limit: 1, Window: time.Hour}
+	s, clock := newTestMemoryStore()
+
+	for key, policy := range map[string]Policy{"short": short, "long": long} {
+		if _, err := s.Take(ctx, key, policy); err != nil {
+			t.Fatalf("Take(%q) error = %v", key, err)
+		}
+	}
+
+	// Before the sweep interval, nothing is swept even if it has refilled.
+	clock.Advance(2 * time.Minute)
+	if _, err := s.Take(ctx, "other", short); err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if _, ok := s.buckets["short"]; !ok {
+		t.Error("bucket swept before the sweep interval")
+	}
+
+	clock.Advance(sweepInterval)
+	if _, err := s.Take(ctx, "other", short); err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if _, ok := s.buckets["short"]; ok {
+		t.Error("refilled bucket not swept")
+	}
+	if _, ok := s.buckets["long"]; !ok {
+		t.Error("bucket still refilling was swept")
+	}
+
+	// A swept bucket starts out full again.
+	res, err := s.Take(ctx, "short", short)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if !res.Allowed {
+		t.Error("Take() on a swept bucket rejected, want allowed")
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+)
+
+// staleBucketAge must exceed every policy window so that only buckets which
+// have refilled completely are deleted.
+const staleBucketAge = 24 * time.Hour
+
+// PostgresStore shares buckets between replicas. Each Take locks the bucket
+// row for the duration of a short transaction.
+type PostgresStore struct {
+	db      *sql.DB
+	queries *database.Queries
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPostgresStore(db *sql.DB, queries *database.Queries) *PostgresStore {
+	return &PostgresStore{
+		db:        db,
+		queries:   queries,
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	now := time.Now().UTC()
+	s.maybeSweep(ctx, now)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+	qtx := s.queries.WithTx(tx)
+
+	err = qtx.EnsureRateLimitBucket(ctx, database.EnsureRateLimitBucketParams{
+		BucketKey: key,
+		Tokens:    float64(policy.Limit),
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	dbBucket, err := qtx.GetRateLimitBucketForUpdate(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokens, res := take(dbBucket.Tokens, dbBucket.UpdatedAt, now, policy)
+	err = qtx.UpdateRateLimitBucket(ctx, database.UpdateRateLimitBucketParams{
+		Tokens:    tokens,
+		UpdatedAt: now,
+		BucketKey: key,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return res, tx.Commit()
+}
+
+func (s *PostgresStore) maybeSweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	// Failing to sweep only leaves extra rows behind, so the error is ignored.
+	_ = s.queries.DeleteRateLimitBucketsUpdatedBefore(ctx, now.Add(-staleBucketAge))
+}
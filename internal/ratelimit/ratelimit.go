@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+type KeyBy string
+
+const (
+	KeyByIP   KeyBy = "ip"
+	KeyByUser KeyBy = "user"
+)
+
+// Policy is a token bucket holding up to Limit tokens that refills completely
+// over Window.
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	KeyBy  KeyBy
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+func (p Policy) refillRate() float64 {
+	return float64(p.Limit) / p.Window.Seconds()
+}
+
+// take refills the bucket for the time elapsed since it was last updated and
+// consumes a token if one is available. It returns the new token count.
+func take(tokens float64, updatedAt, now time.Time, policy Policy) (float64, Result) {
+	rate := policy.refillRate()
+	elapsed := now.Sub(updatedAt).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(float64(policy.Limit), tokens+elapsed*rate)
+	}
+
+	res := Result{Limit: policy.Limit}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = secondsToDuration((float64(policy.Limit) - tokens) / rate)
+	return tokens, res
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 10, Window: 10 * time.Second}
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		tokens     float64
+		elapsed    time.Duration
+		wantTokens float64
+		want       Result
+	}{
+		{
+			name:       "full bucket",
+			tokens:     10,
+			wantTokens: 9,
+			want:       Result{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:       "last token",
+			tokens:     1,
+			wantTokens: 0,
+			want:       Result{Allowed: true, Limit: 10, Remaining: 0, Reset: 10 * time.Second},
+		},
+		{
+			name:       "empty bucket",
+			tokens:     0,
+			wantTokens: 0,
+			want:       Result{Limit: 10, Remaining: 0, Reset: 10 * time.Second, RetryAfter: time.Second},
+		},
+		{
+			name:       "partial refill",
+			tokens:     0,
+			elapsed:    500 * time.Millisecond,
+			wantTokens: 0.5,
+			want:       Result{Limit: 10, Remaining: 0, Reset: 9500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+		},
+		{
+			name:       "refill",
+			tokens:     0,
+			elapsed:    3 * time.Second,
+			wantTokens: 2,
+			want:       Result{Allowed: true, Limit: 10, Remaining: 2, Reset: 8 * time.Second},
+		},
+		{
+			name:       "refill stops at the limit",
+			tokens:     5,
+			elapsed:    time.Hour,
+			wantTokens: 9,
+			want:       Result{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:       "clock going backwards",
+			tokens:     2,
+			elapsed:    -5 * time.Second,
+			wantTokens: 1,
+			want:       Result{Allowed: true, Limit: 10, Remaining: 1, Reset: 9 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, got := take(tt.tokens, updatedAt, updatedAt.Add(tt.elapsed), policy)
+			if tokens != tt.wantTokens {
+				t.Errorf("tokens = %v, want %v", tokens, tt.wantTokens)
+			}
+			if got != tt.want {
+				t.Errorf("result = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTakeBurst(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 5, Window: time.Minute}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tokens := float64(policy.Limit)
+	var res Result
+	for i := 0; i < policy.Limit; i++ {
+		tokens, res = take(tokens, now, now, policy)
+		if !res.Allowed {
+			t.Fatalf("request %d rejected, want allowed", i+1)
+		}
+		if want := policy.Limit - i - 1; res.Remaining != want {
+			t.Errorf("request %d: Remaining = %d, want %d", i+1, res.Remaining, want)
+		}
+	}
+	_, res = take(tokens, now, now, policy)
+	if res.Allowed {
+		t.Fatal("request past the burst allowed, want rejected")
+	}
+	if want := 12 * time.Second; res.RetryAfter != want {
+		t.Errorf("RetryAfter = %v, want %v", res.RetryAfter, want)
+	}
+}
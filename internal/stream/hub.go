@@ -0,0 +1,85 @@
+// Package stream fans events out to live subscribers, such as clients of
+// GET /api/stream, and wakes up when another replica has published some.
+package stream
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Event is already encoded for clients. Events read from a log carry its
+// IDs, so a subscriber can tell which ones it has seen; others have no ID.
+type Event struct {
+	ID     int64
+	Type   string
+	UserID uuid.UUID
+	Data   []byte
+}
+
+// Hub delivers every published event to every subscriber. Publishing never
+// waits: a subscriber whose buffer is full is dropped, and finds its events
+// channel closed.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+type Subscription struct {
+	hub    *Hub
+	events chan Event
+}
+
+// Subscribe starts receiving events published from now on, buffering up to
+// buffer of them.
+func (h *Hub) Subscribe(buffer int) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		events: make(chan Event, buffer),
+	}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Subscribers returns how many subscriptions are open.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops the subscription. It is safe to call more than once, and after
+// the hub dropped it.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subscribers[s]; ok {
+		delete(s.hub.subscribers, s)
+		close(s.events)
+	}
+}
@@ -0,0 +1,58 @@
+package stream
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	minReconnectInterval = 10 * time.Second
+	maxReconnectInterval = time.Minute
+	// pollInterval bounds how late an event can be when a notification is
+	// lost, e.g. while the listener was reconnecting.
+	pollInterval = 30 * time.Second
+)
+
+// Listen calls handle with the payload of every notification arriving on the
+// Postgres channel until ctx is cancelled. It also calls it with an empty
+// payload after every reconnect and every pollInterval, so that a channel
+// whose payloads only say "something changed" can catch up on lost ones.
+func Listen(ctx context.Context, dbURL, channel string, handle func(ctx context.Context, payload string) error) error {
+	listener := pq.NewListener(dbURL, minReconnectInterval, maxReconnectInterval, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.WarnContext(ctx, "Postgres listener event", "channel", channel, "event", ev, "error", err)
+		}
+	})
+	err := listener.Listen(channel)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	go func() {
+		defer listener.Close()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			var payload string
+			select {
+			case <-ctx.Done():
+				return
+			case notification := <-listener.Notify:
+				if notification != nil {
+					payload = notification.Extra
+				}
+			case <-ticker.C:
+				listener.Ping()
+			}
+			err := handle(ctx, payload)
+			if err != nil {
+				slog.ErrorContext(ctx, "Error handling notification", "channel", channel, "error", err)
+			}
+		}
+	}()
+	return nil
+}
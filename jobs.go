@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/export"
+	"github.com/dmytrochumakov/chirpy/internal/imaging"
+	"github.com/dmytrochumakov/chirpy/internal/jobs"
+)
+
+const (
+	dataExportJobInterval   = 10 * time.Second
+	accountPurgeInterval    = time.Hour
+	trendingHashtagInterval = 5 * time.Minute
+	chirpEventPurgeInterval = time.Hour
+	attachmentPurgeInterval = time.Hour
+	attachmentJobInterval   = 5 * time.Second
+	// attachmentClaimTimeout is how long processing an attachment may take
+	// before another worker assumes the first one died and takes over, up to
+	// maxAttachmentAttempts times in all.
+	attachmentClaimTimeout = 10 * time.Minute
+	maxAttachmentAttempts  = 3
+)
+
+func (cfg *apiConfig) startJobs(ctx context.Context) {
+	jobs.Every(ctx, "data_exports", dataExportJobInterval, cfg.runDataExports)
+	jobs.Every(ctx, "account_purge", accountPurgeInterval, cfg.purgeAccounts)
+	jobs.Every(ctx, "trending_hashtags", trendingHashtagInterval, cfg.computeTrendingHashtags)
+	jobs.Every(ctx, "chirp_event_purge", chirpEventPurgeInterval, cfg.purgeChirpEvents)
+	jobs.Every(ctx, "attachment_purge", attachmentPurgeInterval, cfg.purgeAttachments)
+	jobs.Every(ctx, "attachment_processing", attachmentJobInterval, cfg.processAttachments)
+}
+
+// runDataExports builds every pending export. Claiming uses SKIP LOCKED, so
+// several instances can run this job side by side.
+func (cfg *apiConfig) runDataExports(ctx context.Context) error {
+	for {
+		dbExport, err := cfg.db.ClaimPendingDataExport(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		archive, err := export.Build(ctx, cfg.db, cfg.blobs, dbExport.UserID)
+		now := time.Now().UTC()
+		if err != nil {
+			slog.ErrorContext(ctx, "Error building data export", "export_id", dbExport.ID, "error", err)
+			err = cfg.db.FailDataExport(ctx, database.FailDataExportParams{
+				Error:       sqlNullString(err.Error()),
+				CompletedAt: sqlNullTime(now),
+				ID:          dbExport.ID,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = cfg.db.CompleteDataExport(ctx, database.CompleteDataExportParams{
+			Archive:     archive,
+			CompletedAt: sqlNullTime(now),
+			ExpiresAt:   sqlNullTime(now.Add(dataExportExpirationTime)),
+			ID:          dbExport.ID,
+		})
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "Data export ready", "export_id", dbExport.ID, "user_id", dbExport.UserID)
+	}
+}
+
+// purgeAccounts deletes accounts whose grace period has run out, along with
+// everything that cascades from them, and drops expired export archives.
+func (cfg *apiConfig) purgeAccounts(ctx context.Context) error {
+	now := time.Now().UTC()
+	deleted, err := cfg.db.DeleteUsersScheduledBefore(ctx, sqlNullTime(now))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		slog.InfoContext(ctx, "Deleted accounts", "count", deleted)
+	}
+	return cfg.db.DeleteExpiredDataExports(ctx, sqlNullTime(now))
+}
+
+// computeTrendingHashtags ranks the hashtags used in each trending window
+// ending now. Readers always see the latest complete ranking: older ones are
+// only deleted once the new one is in place.
+func (cfg *apiConfig) computeTrendingHashtags(ctx context.Context) error {
+	now := time.Now().UTC()
+	for _, window := range trendingWindows {
+		err := cfg.db.ComputeTrendingHashtags(ctx, database.ComputeTrendingHashtagsParams{
+			TimeWindow: window.Name,
+			ComputedAt: now,
+			Since:      now.Add(-window.Duration),
+			RowLimit:   maxTrendingHashtags,
+		})
+		if err != nil {
+			return err
+		}
+		err = cfg.db.DeleteStaleTrendingHashtags(ctx, database.DeleteStaleTrendingHashtagsParams{
+			TimeWindow: window.Name,
+			ComputedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeChirpEvents trims the chirp event log, which only has to reach back
+// as far as stream clients may resume from.
+func (cfg *apiConfig) purgeChirpEvents(ctx context.Context) error {
+	return cfg.db.DeleteChirpEventsBefore(ctx, time.Now().UTC().Add(-chirpEventRetention))
+}
+
+// purgeAttachments deletes uploads that were never attached to a chirp, and
+// attachments of chirps that have since been deleted. The blobs go first:
+// if deleting the row fails, the next run tries all of them again.
+func (cfg *apiConfig) purgeAttachments(ctx context.Context) error {
+	createdBefore := time.Now().UTC().Add(-attachmentRetention)
+	for {
+		dbAttachments, err := cfg.db.GetStaleAttachments(ctx, createdBefore)
+		if err != nil {
+			return err
+		}
+		variants, err := cfg.attachmentVariants(ctx, dbAttachments)
+		if err != nil {
+			return err
+		}
+		for _, dbAttachment := range dbAttachments {
+			for _, dbVariant := range variants[dbAttachment.ID] {
+				err = cfg.blobs.Delete(ctx, dbVariant.StorageKey)
+				if err != nil {
+					return err
+				}
+			}
+			err = cfg.blobs.Delete(ctx, dbAttachment.StorageKey)
+			if err != nil {
+				return err
+			}
+			err = cfg.db.DeleteAttachment(ctx, dbAttachment.ID)
+			if err != nil {
+				return err
+			}
+		}
+		if len(dbAttachments) > 0 {
+			slog.InfoContext(ctx, "Deleted stale attachments", "count", len(dbAttachments))
+		}
+		if len(dbAttachments) < staleAttachmentBatchSize {
+			return nil
+		}
+	}
+}
+
+// processAttachments processes every pending upload, and those whose
+// processing was claimed more than attachmentClaimTimeout ago but never
+// finished. Like data exports, claiming uses SKIP LOCKED, so several
+// instances can share the work.
+func (cfg *apiConfig) processAttachments(ctx context.Context) error {
+	for {
+		now := time.Now().UTC()
+		dbAttachment, err := cfg.db.ClaimPendingAttachment(ctx, database.ClaimPendingAttachmentParams{
+			ClaimedAt:   sqlNullTime(now),
+			StaleBefore: sqlNullTime(now.Add(-attachmentClaimTimeout)),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// An upload that keeps killing its worker, say by running it out of
+		// memory, is given up on rather than retried forever.
+		if dbAttachment.Attempts > maxAttachmentAttempts {
+			err = fmt.Errorf("gave up after %d attempts", maxAttachmentAttempts)
+		} else {
+			err = cfg.processAttachment(ctx, dbAttachment)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Error processing attachment", "attachment_id", dbAttachment.ID, "error", err)
+			err = cfg.db.FailAttachment(ctx, database.FailAttachmentParams{
+				Error:       sqlNullString(err.Error()),
+				ProcessedAt: sqlNullTime(time.Now().UTC()),
+				ID:          dbAttachment.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// processAttachment re-encodes an upload, which leaves its EXIF data, GPS
+// position included, behind, and stores it next to its thumbnails under
+// attachments/<id>/. GIFs are rebuilt frame by frame, so that animations
+// survive but their extension blocks don't. WebP can only be decoded, so it
+// becomes JPEG, or PNG when transparent; so do thumbnails of any format.
+func (cfg *apiConfig) processAttachment(ctx context.Context, dbAttachment database.Attachment) error {
+	reader, err := cfg.blobs.Get(ctx, dbAttachment.StorageKey)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	img, format, err := imaging.Decode(data, maxAttachmentPixels)
+	if err != nil {
+		return err
+	}
+	stillFormat := imaging.FormatJPEG
+	if !imaging.Opaque(img) {
+		stillFormat = imaging.FormatPNG
+	}
+
+	prefix := "attachments/" + dbAttachment.ID.String() + "/"
+	var original []byte
+	if format == imaging.FormatGIF {
+		original, err = imaging.RebuildGIF(data)
+		if err != nil {
+			return err
+		}
+	} else {
+		if format == imaging.FormatWebP {
+			format = stillFormat
+		}
+		var buf bytes.Buffer
+		err = imaging.Encode(&buf, img, format)
+		if err != nil {
+			return err
+		}
+		original = buf.Bytes()
+	}
+	storageKey := prefix + "original" + format.Extension()
+	err = cfg.blobs.Put(ctx, storageKey, bytes.NewReader(original), format.ContentType())
+	if err != nil {
+		return err
+	}
+
+	bounds := img.Bounds()
+	for _, size := range attachmentVariantSizes {
+		if bounds.Dx() <= size.MaxSize && bounds.Dy() <= size.MaxSize {
+			continue
+		}
+		thumbnail := imaging.Fit(img, size.MaxSize)
+		var buf bytes.Buffer
+		err = imaging.Encode(&buf, thumbnail, stillFormat)
+		if err != nil {
+			return err
+		}
+		variantKey := prefix + size.Name + stillFormat.Extension()
+		err = cfg.blobs.Put(ctx, variantKey, bytes.NewReader(buf.Bytes()), stillFormat.ContentType())
+		if err != nil {
+			return err
+		}
+		err = cfg.db.CreateAttachmentVariant(ctx, database.CreateAttachmentVariantParams{
+			AttachmentID: dbAttachment.ID,
+			Name:         size.Name,
+			StorageKey:   variantKey,
+			ContentType:  stillFormat.ContentType(),
+			Width:        int32(thumbnail.Bounds().Dx()),
+			Height:       int32(thumbnail.Bounds().Dy()),
+			Size:         int64(buf.Len()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = cfg.db.CompleteAttachment(ctx, database.CompleteAttachmentParams{
+		StorageKey:  storageKey,
+		ContentType: format.ContentType(),
+		Size:        int64(len(original)),
+		Width:       sql.NullInt32{Int32: int32(bounds.Dx()), Valid: true},
+		Height:      sql.NullInt32{Int32: int32(bounds.Dy()), Valid: true},
+		Blurhash:    sqlNullString(imaging.Blurhash(img, 4, 3)),
+		ProcessedAt: sqlNullTime(time.Now().UTC()),
+		ID:          dbAttachment.ID,
+	})
+	if err != nil {
+		return err
+	}
+	// The upload is only needed until it has been replaced.
+	if dbAttachment.StorageKey != storageKey {
+		err = cfg.blobs.Delete(ctx, dbAttachment.StorageKey)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error deleting processed upload", "attachment_id", dbAttachment.ID, "error", err)
+		}
+	}
+	return nil
+}
@@ -1,79 +1,260 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
+	"slices"
 	"sync/atomic"
+	"time"
 
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/blob"
 	"github.com/dmytrochumakov/chirpy/internal/database"
+	"github.com/dmytrochumakov/chirpy/internal/lockout"
+	"github.com/dmytrochumakov/chirpy/internal/logging"
+	"github.com/dmytrochumakov/chirpy/internal/mailer"
+	"github.com/dmytrochumakov/chirpy/internal/metrics"
+	"github.com/dmytrochumakov/chirpy/internal/passwordpolicy"
+	"github.com/dmytrochumakov/chirpy/internal/ratelimit"
+	"github.com/dmytrochumakov/chirpy/internal/stream"
+	"github.com/dmytrochumakov/chirpy/internal/tracing"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	envPlatform    string
 	db             *database.Queries
+	sqlDB          *sql.DB
 	jwtSecret      string
 	polkaKey       string
+	metrics        *metrics.Metrics
+
+	rateLimiter        ratelimit.Store
+	trustedProxyHops   int
+	loginGuard         *lockout.Guard
+	mailer             mailer.Mailer
+	baseURL            string
+	passwordPolicy     *passwordpolicy.Policy
+	chirpStream        *stream.Hub
+	notificationStream *stream.Hub
+	wsConnections      wsConnectionCounter
+	blobs              blob.Store
 }
 
 func main() {
 	err := godotenv.Load(".env")
 	if err != nil {
-		log.Fatal(err)
-		return
+		fatal("Error loading .env", err)
+	}
+
+	logger := logging.New(os.Stdout, logging.Format(os.Getenv("LOG_FORMAT")), logging.ParseLevel(os.Getenv("LOG_LEVEL")))
+	slog.SetDefault(logger)
+
+	traceExporter, err := tracing.NewExporter(context.Background(), tracing.Exporter(os.Getenv("OTEL_TRACES_EXPORTER")), os.Stdout)
+	if err != nil {
+		fatal("Error creating trace exporter", err)
+	}
+	shutdownTracing, err := tracing.Setup(traceExporter)
+	if err != nil {
+		fatal("Error setting up tracing", err)
 	}
 
 	dbURL := os.Getenv("DB_URL")
 	envPlatform := os.Getenv("PLATFORM")
 	jwtSecret := os.Getenv("JWT_SECRET")
 	polkaKey := os.Getenv("POLKA_KEY")
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
-		log.Fatal(err)
-		return
+		fatal("Error opening database", err)
 	}
 
 	const filepathRoot = "."
 	const port = "8080"
-	dbQueries := database.New(db)
+	appMetrics := metrics.New(db)
+	dbQueries := database.New(tracing.TraceDB(logging.LogDB(appMetrics.InstrumentDB(db))))
+
+	if len(os.Args) > 1 {
+		err = runCommand(context.Background(), dbQueries, os.Args[1:])
+		if err != nil {
+			fatal("Command failed", err)
+		}
+		return
+	}
+
+	passwordHasher, err := auth.PasswordHasherFromEnv()
+	if err != nil {
+		fatal("Error configuring password hashing", err)
+	}
+	auth.SetPasswordHasher(passwordHasher)
+
+	passwordPolicy, err := passwordPolicyFromEnv(passwordHasher)
+	if err != nil {
+		fatal("Error configuring password policy", err)
+	}
+
+	appMailer, err := mailer.FromEnv()
+	if err != nil {
+		fatal("Error creating mailer", err)
+	}
+
+	blobStore, err := blob.FromEnv(baseURL)
+	if err != nil {
+		fatal("Error creating blob store", err)
+	}
+
+	trustedProxyHops, err := trustedProxyHopsFromEnv()
+	if err != nil {
+		fatal("Error configuring proxy headers", err)
+	}
+
+	var rateLimiter ratelimit.Store = ratelimit.NewMemoryStore()
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		rateLimiter = ratelimit.NewPostgresStore(db, dbQueries)
+	}
 
 	apiCfg := &apiConfig{
 		fileserverHits: atomic.Int32{},
 		envPlatform:    envPlatform,
 		db:             dbQueries,
+		sqlDB:          db,
 		jwtSecret:      jwtSecret,
 		polkaKey:       polkaKey,
+		metrics:        appMetrics,
+
+		rateLimiter:        rateLimiter,
+		trustedProxyHops:   trustedProxyHops,
+		loginGuard:         lockout.NewGuard(dbQueries, lockout.AccountPolicy, lockout.IPPolicy),
+		mailer:             appMailer,
+		baseURL:            baseURL,
+		passwordPolicy:     passwordPolicy,
+		chirpStream:        stream.NewHub(),
+		notificationStream: stream.NewHub(),
+		blobs:              blobStore,
 	}
+	appMetrics.RegisterGaugeFunc("fileserver_hits", "Number of fileserver hits since the last reset.", func() float64 {
+		return float64(apiCfg.fileserverHits.Load())
+	})
+	appMetrics.RegisterGaugeFunc("stream_subscribers", "Number of clients connected to the chirp stream or WebSocket API.", func() float64 {
+		return float64(apiCfg.chirpStream.Subscribers())
+	})
 
 	mux := http.NewServeMux()
-	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot)))))
+	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(noListingFS{http.Dir(filepathRoot)}))))
 	mux.HandleFunc("GET /api/healthz", handlerHealthz)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
+	mux.HandleFunc("GET /admin/metrics", apiCfg.middlewareRequireRole(RoleAdmin, apiCfg.handlerMetrics))
+	mux.Handle("GET /metrics", appMetrics.Handler())
+	mux.HandleFunc("POST /admin/reset", apiCfg.middlewareRequireRole(RoleAdmin, apiCfg.handlerReset))
+	mux.HandleFunc("GET /admin/users", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerAdminSearchUsers))
+	mux.HandleFunc("GET /admin/users/{userID}", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerAdminGetUser))
+	mux.HandleFunc("PUT /admin/users/{userID}/role", apiCfg.middlewareRequireRole(RoleAdmin, apiCfg.handlerAdminSetRole))
+	mux.HandleFunc("POST /admin/users/{userID}/suspension", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerAdminSuspendUser))
+	mux.HandleFunc("DELETE /admin/users/{userID}/suspension", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerAdminUnsuspendUser))
+	mux.HandleFunc("PUT /admin/users/{userID}/chirpy_red", apiCfg.middlewareRequireRole(RoleAdmin, apiCfg.handlerAdminSetChirpyRed))
+	mux.HandleFunc("DELETE /admin/chirps/{chirpID}", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerAdminDeleteChirp))
+	mux.HandleFunc("GET /admin/reports", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerGetModerationQueue))
+	mux.HandleFunc("GET /admin/chirps/{chirpID}/moderation", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerGetChirpModeration))
+	mux.HandleFunc("POST /admin/chirps/{chirpID}/moderation", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerModerateChirp))
+	mux.HandleFunc("GET /admin/appeals", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerGetPendingAppeals))
+	mux.HandleFunc("POST /admin/appeals/{appealID}", apiCfg.middlewareRequireRole(RoleModerator, apiCfg.handlerResolveAppeal))
 	mux.HandleFunc("POST /api/validate_chirp", handlerValidateChirp)
-	mux.HandleFunc("POST /api/users", apiCfg.handlerCreateUser)
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerCreateChirp)
+	mux.HandleFunc("POST /api/users", apiCfg.middlewareRateLimit(rateLimitSignup, apiCfg.handlerCreateUser))
+	mux.HandleFunc("POST /api/chirps", apiCfg.middlewareRateLimit(rateLimitCreateChirp, apiCfg.handlerCreateChirp))
+	mux.HandleFunc("POST /api/attachments", apiCfg.middlewareRateLimit(rateLimitUploadAttachment, apiCfg.handlerUploadAttachment))
+	mux.HandleFunc("GET /api/blobs/{key...}", apiCfg.handlerGetBlob)
 	mux.HandleFunc("GET /api/chirps", apiCfg.handlerGetAllChirps)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerGetChirpByID)
-	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
-	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
+	mux.HandleFunc("GET /api/stream", apiCfg.handlerStream)
+	mux.HandleFunc("GET /api/ws", apiCfg.handlerWebSocket)
+	mux.HandleFunc("GET /api/hashtags/{tag}", apiCfg.handlerGetHashtagChirps)
+	mux.HandleFunc("GET /api/trending/hashtags", apiCfg.handlerGetTrendingHashtags)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", apiCfg.middlewareRateLimit(rateLimitReportChirp, apiCfg.handlerReportChirp))
+	mux.HandleFunc("POST /api/login", apiCfg.middlewareRateLimit(rateLimitLogin, apiCfg.handlerLogin))
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.middlewareRateLimit(rateLimitLogin, apiCfg.handlerLoginMFA))
+	mux.HandleFunc("POST /api/refresh", apiCfg.middlewareRateLimit(rateLimitRefresh, apiCfg.handlerRefresh))
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
-	mux.HandleFunc("PUT /api/users", apiCfg.handlerUpdateUserEmailAndPassword)
+	mux.HandleFunc("POST /api/password/forgot", apiCfg.middlewareRateLimit(rateLimitPasswordForgot, apiCfg.handlerForgotPassword))
+	mux.HandleFunc("POST /api/password/reset", apiCfg.middlewareRateLimit(rateLimitPasswordReset, apiCfg.handlerResetPassword))
+	mux.HandleFunc("PATCH /api/users", apiCfg.handlerUpdateUser)
+	mux.HandleFunc("GET /api/users/{handle}", apiCfg.handlerGetUserProfile)
+	mux.HandleFunc("POST /api/users/{userID}/block", apiCfg.handlerBlockUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.handlerUnblockUser)
+	mux.HandleFunc("POST /api/users/{userID}/mute", apiCfg.handlerMuteUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", apiCfg.handlerUnmuteUser)
+	mux.HandleFunc("PUT /api/users/password", apiCfg.handlerChangePassword)
+	mux.HandleFunc("POST /api/users/verify_email", apiCfg.handlerVerifyEmail)
+	mux.HandleFunc("POST /api/users/verify_email/resend", apiCfg.middlewareRateLimit(rateLimitEmailVerification, apiCfg.handlerResendEmailVerification))
+	mux.HandleFunc("POST /api/users/totp", apiCfg.handlerEnrollTOTP)
+	mux.HandleFunc("POST /api/users/totp/confirm", apiCfg.handlerConfirmTOTP)
+	mux.HandleFunc("POST /api/users/totp/recovery_codes", apiCfg.handlerRegenerateRecoveryCodes)
+	mux.HandleFunc("DELETE /api/users/me", apiCfg.handlerDeleteAccount)
+	mux.HandleFunc("POST /api/users/me/cancel_deletion", apiCfg.handlerCancelAccountDeletion)
+	mux.HandleFunc("GET /api/users/me/export", apiCfg.handlerExportAccount)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
+	mux.HandleFunc("GET /api/moderation/actions", apiCfg.handlerGetMyModerationActions)
+	mux.HandleFunc("POST /api/moderation/actions/{actionID}/appeal", apiCfg.handlerAppealModerationAction)
+	mux.HandleFunc("POST /api/tokens", apiCfg.handlerCreatePersonalAccessToken)
+	mux.HandleFunc("GET /api/tokens", apiCfg.handlerGetPersonalAccessTokens)
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.handlerRevokePersonalAccessToken)
+	mux.HandleFunc("POST /api/oauth/clients", apiCfg.handlerCreateOAuthClient)
+	mux.HandleFunc("GET /api/oauth/clients", apiCfg.handlerGetOAuthClients)
+	mux.HandleFunc("DELETE /api/oauth/clients/{clientID}", apiCfg.handlerDeleteOAuthClient)
+	mux.HandleFunc("GET /api/oauth/consents", apiCfg.handlerGetOAuthConsents)
+	mux.HandleFunc("DELETE /api/oauth/consents/{clientID}", apiCfg.handlerDeleteOAuthConsent)
+	mux.HandleFunc("GET /api/oauth/authorize", apiCfg.handlerGetOAuthAuthorization)
+	mux.HandleFunc("POST /api/oauth/authorize", apiCfg.handlerOAuthAuthorize)
+	mux.HandleFunc("POST /oauth/token", apiCfg.middlewareRateLimit(rateLimitOAuth, apiCfg.handlerOAuthToken))
+	mux.HandleFunc("POST /oauth/revoke", apiCfg.middlewareRateLimit(rateLimitOAuth, apiCfg.handlerOAuthRevoke))
+	mux.HandleFunc("POST /oauth/introspect", apiCfg.middlewareRateLimit(rateLimitOAuth, apiCfg.handlerOAuthIntrospect))
+	mux.HandleFunc("GET /api/notifications", apiCfg.handlerGetNotifications)
+	mux.HandleFunc("GET /api/notifications/unread_count", apiCfg.handlerGetUnreadNotificationCount)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", apiCfg.handlerMarkNotificationRead)
+	mux.HandleFunc("POST /api/notifications/read_all", apiCfg.handlerMarkAllNotificationsRead)
+	mux.HandleFunc("GET /api/notifications/preferences", apiCfg.handlerGetNotificationPreferences)
+	mux.HandleFunc("PUT /api/notifications/preferences", apiCfg.handlerUpdateNotificationPreferences)
+	mux.HandleFunc("POST /api/conversations", apiCfg.handlerCreateConversation)
+	mux.HandleFunc("GET /api/conversations", apiCfg.handlerGetConversations)
+	mux.HandleFunc("GET /api/conversations/{conversationID}", apiCfg.handlerGetConversation)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.handlerGetMessages)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.middlewareRateLimit(rateLimitSendMessage, apiCfg.handlerSendMessage))
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.handlerMarkConversationRead)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerWebhooks)
 
 	server := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: tracing.Middleware(middlewareRequestID(apiCfg.middlewareObserve(mux))),
 	}
 
-	log.Printf("Serving on port: %s\n", port)
-	log.Fatal(server.ListenAndServe())
+	apiCfg.startJobs(context.Background())
+	err = apiCfg.startChirpStream(context.Background(), dbURL)
+	if err != nil {
+		fatal("Error starting chirp stream", err)
+	}
+	err = apiCfg.startNotificationStream(context.Background(), dbURL)
+	if err != nil {
+		fatal("Error starting notification stream", err)
+	}
+
+	slog.Info("Serving", "port", port)
+	err = server.ListenAndServe()
+	shutdownTracing(context.Background())
+	fatal("Server stopped", err)
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
 
 func writeCleanedBody(w http.ResponseWriter, cleanedBody string) {
@@ -87,7 +268,7 @@ func writeCleanedBody(w http.ResponseWriter, cleanedBody string) {
 
 	dat, err := json.Marshal(resp)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
+		slog.Error("Error marshalling JSON", "error", err)
 		write500Error(w)
 		return
 	}
@@ -105,14 +286,14 @@ func writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{})
 
 	response, err := json.Marshal(data)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
+		slog.Error("Error marshalling JSON", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	_, writeErr := w.Write(response)
 	if writeErr != nil {
-		log.Printf("Error writing response: %s", writeErr)
+		slog.Error("Error writing response", "error", writeErr)
 	}
 }
 
@@ -127,7 +308,7 @@ func writeError(w http.ResponseWriter, code int, error string) {
 
 	dat, err := json.Marshal(respError)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
+		slog.Error("Error marshalling JSON", "error", err)
 		write500Error(w)
 		return
 	}
@@ -155,3 +336,133 @@ func DecodeJSON[T any](r *http.Request, target *T) error {
 	defer r.Body.Close()
 	return decoder.Decode(target)
 }
+
+func logRequestError(r *http.Request, msg string, err error) {
+	slog.ErrorContext(r.Context(), msg, "error", err)
+}
+
+func logRequestWarn(r *http.Request, msg string, err error) {
+	slog.WarnContext(r.Context(), msg, "error", err)
+}
+
+func (cfg *apiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		logRequestWarn(r, "Missing bearer token", err)
+		return uuid.Nil, err
+	}
+	return cfg.validateAccessToken(r, token)
+}
+
+func (cfg *apiConfig) validateAccessToken(r *http.Request, token string) (uuid.UUID, error) {
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		logRequestWarn(r, "Invalid access token", err)
+		return uuid.Nil, err
+	}
+	logging.SetUserID(r.Context(), userID.String())
+	return userID, nil
+}
+
+var errMissingScope = errors.New("token is missing the required scope")
+
+// authenticateScope is authenticate for routes that also accept personal
+// access tokens, as long as the token was granted scope.
+func (cfg *apiConfig) authenticateScope(r *http.Request, scope auth.Scope) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		logRequestWarn(r, "Missing bearer token", err)
+		return uuid.Nil, err
+	}
+	return cfg.validateScopedToken(r, token, scope)
+}
+
+// validateScopedToken accepts first-party access tokens, which carry every
+// scope, and personal access tokens and OAuth access tokens granted scope.
+func (cfg *apiConfig) validateScopedToken(r *http.Request, token string, scope auth.Scope) (uuid.UUID, error) {
+	if auth.IsPersonalAccessToken(token) {
+		return cfg.validatePersonalAccessToken(r, token, scope)
+	}
+	if oauthToken, err := auth.ValidateOAuthJWT(token, cfg.jwtSecret); err == nil {
+		logging.SetUserID(r.Context(), oauthToken.UserID.String())
+		if !cfg.hasOAuthConsent(r, oauthToken) {
+			slog.WarnContext(r.Context(), "OAuth access token used after consent was revoked", "client_id", oauthToken.ClientID)
+			return uuid.Nil, errors.New("consent revoked")
+		}
+		if !slices.Contains(oauthToken.Scopes, string(scope)) {
+			slog.WarnContext(r.Context(), "OAuth access token is missing scope", "client_id", oauthToken.ClientID, "scope", scope)
+			return uuid.Nil, errMissingScope
+		}
+		return oauthToken.UserID, nil
+	}
+	return cfg.validateAccessToken(r, token)
+}
+
+func (cfg *apiConfig) validatePersonalAccessToken(r *http.Request, token string, scope auth.Scope) (uuid.UUID, error) {
+	dbToken, err := cfg.db.GetActivePersonalAccessTokenByHash(r.Context(), auth.HashToken(token))
+	if err != nil {
+		logRequestWarn(r, "Invalid personal access token", err)
+		return uuid.Nil, err
+	}
+	logging.SetUserID(r.Context(), dbToken.UserID.String())
+	if !slices.Contains(dbToken.Scopes, string(scope)) {
+		slog.WarnContext(r.Context(), "Personal access token is missing scope", "token_id", dbToken.ID, "scope", scope)
+		return uuid.Nil, errMissingScope
+	}
+	err = cfg.db.TouchPersonalAccessToken(r.Context(), database.TouchPersonalAccessTokenParams{
+		LastUsedAt: sqlNullTime(time.Now().UTC()),
+		ID:         dbToken.ID,
+	})
+	if err != nil {
+		logRequestError(r, "Error updating personal access token", err)
+	}
+	return dbToken.UserID, nil
+}
+
+// writeAuthError answers a failed authenticateScope: 403 when the token is
+// fine but lacks the scope, 401 otherwise.
+func writeAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMissingScope) {
+		writeError(w, http.StatusForbidden, "Token is missing the required scope")
+		return
+	}
+	write401Error(w)
+}
+
+// inTx runs fn with queries that share one transaction, which is committed
+// if fn succeeds and rolled back otherwise.
+func (cfg *apiConfig) inTx(ctx context.Context, fn func(qtx *database.Queries) error) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = fn(cfg.db.WithTx(tx))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func sqlNullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func sqlNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// userConflictMessage explains which unique column of users an insert or
+// update collided with.
+func userConflictMessage(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "users_handle_lower_idx" {
+		return "Handle is already taken"
+	}
+	return "Email is already in use"
+}
@@ -1,6 +1,41 @@
 package main
 
-import "net/http"
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmytrochumakov/chirpy/internal/auth"
+	"github.com/dmytrochumakov/chirpy/internal/logging"
+	"github.com/dmytrochumakov/chirpy/internal/ratelimit"
+	"github.com/dmytrochumakov/chirpy/internal/tracing"
+	"github.com/google/uuid"
+)
+
+const (
+	headerRequestID    = "X-Request-ID"
+	maxRequestIDLength = 128
+	unmatchedRoute     = "unmatched"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -8,3 +43,228 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func middlewareRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(headerRequestID)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(headerRequestID, requestID)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// middlewareObserve must wrap the mux directly: the mux sets r.Pattern on the
+// request it is given, which earlier middleware only see a copy of.
+func (cfg *apiConfig) middlewareObserve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		duration := time.Since(start)
+
+		route := r.Pattern
+		if route == "" {
+			route = unmatchedRoute
+		}
+		cfg.metrics.ObserveHTTPRequest(r.Method, route, rec.status, duration)
+		tracing.SetRoute(r.Context(), route)
+		tracing.SetStatus(r.Context(), rec.status)
+
+		attrs := []any{
+			"method", r.Method,
+			"route", route,
+			"status", rec.status,
+			"latency_ms", float64(duration.Microseconds()) / 1000,
+		}
+		if userID := logging.UserID(r.Context()); userID != "" {
+			attrs = append(attrs, "user_id", userID)
+		}
+		slog.InfoContext(r.Context(), "request completed", attrs...)
+	})
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	rateLimitLogin = ratelimit.Policy{
+		Name:   "login",
+		Limit:  5,
+		Window: time.Minute,
+		KeyBy:  ratelimit.KeyByIP,
+	}
+	rateLimitSignup = ratelimit.Policy{
+		Name:   "signup",
+		Limit:  10,
+		Window: time.Hour,
+		KeyBy:  ratelimit.KeyByIP,
+	}
+	rateLimitRefresh = ratelimit.Policy{
+		Name:   "refresh",
+		Limit:  30,
+		Window: time.Minute,
+		KeyBy:  ratelimit.KeyByIP,
+	}
+	rateLimitPasswordForgot = ratelimit.Policy{
+		Name:   "password_forgot",
+		Limit:  5,
+		Window: time.Hour,
+		KeyBy:  ratelimit.KeyByIP,
+	}
+	rateLimitPasswordReset = ratelimit.Policy{
+		Name:   "password_reset",
+		Limit:  10,
+		Window: time.Hour,
+		KeyBy:  ratelimit.KeyByIP,
+	}
+	rateLimitEmailVerification = ratelimit.Policy{
+		Name:   "email_verification",
+		Limit:  5,
+		Window: time.Hour,
+		KeyBy:  ratelimit.KeyByUser,
+	}
+	rateLimitOAuth = ratelimit.Policy{
+		Name:   "oauth",
+		Limit:  60,
+		Window: time.Minute,
+		KeyBy:  ratelimit.KeyByIP,
+	}
+	rateLimitReportChirp = ratelimit.Policy{
+		Name:   "report_chirp",
+		Limit:  20,
+		Window: time.Hour,
+		KeyBy:  ratelimit.KeyByUser,
+	}
+	rateLimitCreateChirp = ratelimit.Policy{
+		Name:   "create_chirp",
+		Limit:  30,
+		Window: time.Minute,
+		KeyBy:  ratelimit.KeyByUser,
+	}
+	rateLimitUploadAttachment = ratelimit.Policy{
+		Name:   "upload_attachment",
+		Limit:  30,
+		Window: time.Hour,
+		KeyBy:  ratelimit.KeyByUser,
+	}
+	rateLimitSendMessage = ratelimit.Policy{
+		Name:   "send_message",
+		Limit:  60,
+		Window: time.Minute,
+		KeyBy:  ratelimit.KeyByUser,
+	}
+	// rateLimitWebSocketMessage applies to each WebSocket connection on its
+	// own, on top of the limits of what the messages do.
+	rateLimitWebSocketMessage = ratelimit.Policy{
+		Name:   "websocket_message",
+		Limit:  20,
+		Window: 10 * time.Second,
+	}
+)
+
+func (cfg *apiConfig) middlewareRateLimit(policy ratelimit.Policy, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := policy.Name + ":" + cfg.rateLimitSubject(r, policy.KeyBy)
+		res, err := cfg.rateLimiter.Take(r.Context(), key, policy)
+		if err != nil {
+			// Fail open: an unavailable store shouldn't take the API down with it.
+			logRequestError(r, "Error checking rate limit", err)
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+		if !res.Allowed {
+			slog.InfoContext(r.Context(), "Rate limit exceeded", "policy", policy.Name, "key", key)
+			cfg.metrics.ObserveRateLimited(policy.Name)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			writeError(w, http.StatusTooManyRequests, "429 Too Many Requests")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rateLimitSubject identifies who a request is charged to. Requests to
+// user-keyed routes without a valid access token fall back to the client IP;
+// the handler rejects them anyway.
+func (cfg *apiConfig) rateLimitSubject(r *http.Request, keyBy ratelimit.KeyBy) string {
+	if keyBy == ratelimit.KeyByUser {
+		if token, err := auth.GetBearerToken(r.Header); err == nil {
+			if auth.IsPersonalAccessToken(token) {
+				if dbToken, err := cfg.db.GetActivePersonalAccessTokenByHash(r.Context(), auth.HashToken(token)); err == nil {
+					return "user:" + dbToken.UserID.String()
+				}
+			} else if userID, err := auth.ValidateJWT(token, cfg.jwtSecret); err == nil {
+				return "user:" + userID.String()
+			} else if oauthToken, err := auth.ValidateOAuthJWT(token, cfg.jwtSecret); err == nil {
+				return "user:" + oauthToken.UserID.String()
+			}
+		}
+	}
+	return "ip:" + cfg.clientIP(r)
+}
+
+// trustedProxyHopsFromEnv returns how many reverse proxies in front of the
+// server append to X-Forwarded-For: TRUSTED_PROXY_HOPS, or 1 when just
+// TRUST_PROXY_HEADERS=true is set. 0 means the header is ignored.
+func trustedProxyHopsFromEnv() (int, error) {
+	value := os.Getenv("TRUSTED_PROXY_HOPS")
+	if value == "" {
+		if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	hops, err := strconv.Atoi(value)
+	if err != nil || hops < 0 {
+		return 0, fmt.Errorf("invalid TRUSTED_PROXY_HOPS: %q", value)
+	}
+	return hops, nil
+}
+
+// clientIP returns the address the request came from. Behind trusted
+// proxies, that is the X-Forwarded-For entry the outermost of them appended,
+// counting from the right: entries further left come from the client and
+// can be anything.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	if cfg.trustedProxyHops > 0 {
+		var entries []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(header, ",") {
+				entries = append(entries, strings.TrimSpace(entry))
+			}
+		}
+		if len(entries) > 0 {
+			// With fewer entries than hops, the request skipped the outer
+			// proxies, and the left-most entry was still appended by ours.
+			entry := entries[max(0, len(entries)-cfg.trustedProxyHops)]
+			if ip := net.ParseIP(entry); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
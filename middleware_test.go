@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dmytrochumakov/chirpy/internal/metrics"
+	"github.com/dmytrochumakov/chirpy/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestTracingRecordsSpanPerRequest(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	_, err := tracing.Setup(exporter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("postgres", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cfg := &apiConfig{metrics: metrics.New(db)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler := tracing.Middleware(middlewareRequestID(cfg.middlewareObserve(mux)))
+
+	tests := []struct {
+		method string
+		path   string
+		route  string
+		status int
+	}{
+		{method: http.MethodGet, path: "/api/chirps/1", route: "GET /api/chirps/{chirpID}", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/api/chirps/2", route: "GET /api/chirps/{chirpID}", status: http.StatusNotFound},
+		{method: http.MethodPost, path: "/api/chirps", route: "POST /api/chirps", status: http.StatusInternalServerError},
+		{method: http.MethodGet, path: "/nowhere", route: unmatchedRoute, status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+	}
+	err = otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != len(tests) {
+		t.Fatalf("got %d spans, want %d", len(spans), len(tests))
+	}
+	for i, tt := range tests {
+		span := spans[i]
+		if span.Name != tt.route {
+			t.Errorf("span %d: name = %q, want %q", i, span.Name, tt.route)
+		}
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, attr := range span.Attributes {
+			attrs[attr.Key] = attr.Value
+		}
+		if got := attrs[semconv.HTTPRouteKey].AsString(); got != tt.route {
+			t.Errorf("span %d: %s = %q, want %q", i, semconv.HTTPRouteKey, got, tt.route)
+		}
+		if got := attrs[semconv.HTTPResponseStatusCodeKey].AsInt64(); got != int64(tt.status) {
+			t.Errorf("span %d: %s = %d, want %d", i, semconv.HTTPResponseStatusCodeKey, got, tt.status)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		hops       int
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "no proxy", hops: 0, remoteAddr: "203.0.113.7:4321", want: "203.0.113.7"},
+		{name: "header ignored without trusted proxies", hops: 0, remoteAddr: "203.0.113.7:4321", forwarded: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "one proxy", hops: 1, remoteAddr: "10.0.0.1:80", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofed entries are skipped", hops: 1, remoteAddr: "10.0.0.1:80", forwarded: []string{"1.2.3.4, 5.6.7.8, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "two proxies", hops: 2, remoteAddr: "10.0.0.1:80", forwarded: []string{"1.2.3.4, 198.51.100.1, 10.0.0.2"}, want: "198.51.100.1"},
+		{name: "fewer entries than hops", hops: 2, remoteAddr: "10.0.0.1:80", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "entries across headers", hops: 1, remoteAddr: "10.0.0.1:80", forwarded: []string{"1.2.3.4", "198.51.100.1"}, want: "198.51.100.1"},
+		{name: "garbage falls back to the peer", hops: 1, remoteAddr: "10.0.0.1:80", forwarded: []string{"1.2.3.4, not-an-ip"}, want: "10.0.0.1"},
+		{name: "IPv6", hops: 1, remoteAddr: "[::1]:80", forwarded: []string{"2001:db8::1"}, want: "2001:db8::1"},
+		{name: "three proxies", hops: 3, remoteAddr: "10.0.0.1:80", forwarded: []string{"1.2.3.4, 198.51.100.1, 10.0.0.3, 10.0.0.2"}, want: "198.51.100.1"},
+		{name: "three proxies across headers", hops: 3, remoteAddr: "10.0.0.1:80", forwarded: []string{"1.2.3.4, 198.51.100.1", "10.0.0.3", "10.0.0.2"}, want: "198.51.100.1"},
+		{name: "no header behind a proxy", hops: 1, remoteAddr: "10.0.0.1:80", want: "10.0.0.1"},
+		{name: "empty header", hops: 1, remoteAddr: "10.0.0.1:80", forwarded: []string{""}, want: "10.0.0.1"},
+		{name: "empty entry", hops: 2, remoteAddr: "10.0.0.1:80", forwarded: []string{"198.51.100.1, , 10.0.0.2"}, want: "10.0.0.1"},
+		{name: "entry with a port", hops: 1, remoteAddr: "10.0.0.1:80", forwarded: []string{"198.51.100.1:4321"}, want: "10.0.0.1"},
+		{name: "entry with spaces", hops: 1, remoteAddr: "10.0.0.1:80", forwarded: []string{"1.2.3.4 ,  198.51.100.1  "}, want: "198.51.100.1"},
+		{name: "IPv4-mapped IPv6", hops: 1, remoteAddr: "10.0.0.1:80", forwarded: []string{"::ffff:198.51.100.1"}, want: "198.51.100.1"},
+		{name: "peer without a port", hops: 0, remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &apiConfig{trustedProxyHops: tt.hops}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, forwarded := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", forwarded)
+			}
+			if got := cfg.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
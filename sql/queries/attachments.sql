@@ -0,0 +1,105 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments(id, user_id, storage_key, content_type, size, created_at)
+VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: CountUnattachedAttachments :one
+SELECT COUNT(*) FROM attachments
+WHERE user_id = sqlc.arg(user_id)
+AND chirp_id IS NULL
+AND status <> 'failed'
+AND id = ANY(sqlc.arg(ids)::UUID[]);
+
+-- name: AttachAttachmentsToChirp :execrows
+UPDATE attachments
+SET chirp_id = sqlc.arg(chirp_id),
+    attached_at = sqlc.arg(attached_at),
+    position = array_position(sqlc.arg(ids)::UUID[], id)
+WHERE user_id = sqlc.arg(user_id)
+AND chirp_id IS NULL
+AND status <> 'failed'
+AND id = ANY(sqlc.arg(ids)::UUID[]);
+
+-- name: GetAttachmentsByChirpIDs :many
+SELECT * FROM attachments
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::UUID[])
+ORDER BY chirp_id, position;
+
+-- name: GetAttachmentsByUserID :many
+SELECT * FROM attachments
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetStaleAttachments :many
+SELECT * FROM attachments
+WHERE chirp_id IS NULL
+AND created_at < sqlc.arg(created_before)
+ORDER BY created_at
+LIMIT 100;
+
+-- name: DeleteAttachment :exec
+DELETE FROM attachments
+WHERE id = $1;
+
+-- name: ClaimPendingAttachment :one
+UPDATE attachments
+SET status = 'processing',
+    claimed_at = sqlc.arg(claimed_at),
+    attempts = attempts + 1
+WHERE id = (
+    SELECT id FROM attachments
+    WHERE status = 'pending'
+    OR (status = 'processing' AND (claimed_at IS NULL OR claimed_at < sqlc.arg(stale_before)))
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteAttachment :exec
+UPDATE attachments
+SET status = 'ready',
+    storage_key = $1,
+    content_type = $2,
+    size = $3,
+    width = $4,
+    height = $5,
+    blurhash = $6,
+    processed_at = $7
+WHERE id = $8;
+
+-- name: FailAttachment :exec
+UPDATE attachments
+SET status = 'failed', error = $1, processed_at = $2
+WHERE id = $3;
+
+-- name: CreateAttachmentVariant :exec
+INSERT INTO attachment_variants(attachment_id, name, storage_key, content_type, width, height, size)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (attachment_id, name) DO UPDATE
+SET storage_key = EXCLUDED.storage_key,
+    content_type = EXCLUDED.content_type,
+    width = EXCLUDED.width,
+    height = EXCLUDED.height,
+    size = EXCLUDED.size;
+
+-- name: GetAttachmentVariantsByAttachmentIDs :many
+SELECT * FROM attachment_variants
+WHERE attachment_id = ANY(sqlc.arg(attachment_ids)::UUID[])
+ORDER BY attachment_id, width;
+
+-- name: GetServableBlobContentType :one
+SELECT blobs.content_type FROM (
+    SELECT attachments.id AS attachment_id, attachments.storage_key, attachments.content_type FROM attachments
+    UNION ALL
+    SELECT attachment_variants.attachment_id, attachment_variants.storage_key, attachment_variants.content_type FROM attachment_variants
+) AS blobs
+JOIN attachments ON attachments.id = blobs.attachment_id
+LEFT JOIN chirps ON chirps.id = attachments.chirp_id
+WHERE blobs.storage_key = $1
+AND attachments.status = 'ready'
+AND (chirps.moderation_status = 'visible' OR attachments.attached_at IS NULL);
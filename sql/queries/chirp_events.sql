@@ -0,0 +1,32 @@
+-- name: CreateChirpEvent :one
+INSERT INTO chirp_events(type, chirp_id, user_id, created_at)
+VALUES (
+    $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: NotifyChirpEvents :exec
+SELECT pg_notify('chirp_events', '');
+
+-- name: GetLatestChirpEventID :one
+SELECT COALESCE(MAX(id), 0)::BIGINT AS id FROM chirp_events;
+
+-- name: GetChirpEventsAfter :many
+SELECT
+    chirp_events.id,
+    chirp_events.type,
+    chirp_events.chirp_id,
+    chirp_events.user_id,
+    chirp_events.created_at,
+    chirps.body,
+    chirps.created_at AS chirp_created_at,
+    chirps.updated_at AS chirp_updated_at
+FROM chirp_events
+LEFT JOIN chirps ON chirps.id = chirp_events.chirp_id AND chirps.moderation_status = 'visible'
+WHERE chirp_events.id > sqlc.arg(after_id)
+ORDER BY chirp_events.id
+LIMIT sqlc.arg(row_limit);
+
+-- name: DeleteChirpEventsBefore :exec
+DELETE FROM chirp_events
+WHERE created_at < $1;
@@ -0,0 +1,33 @@
+-- name: CreateChirpReport :one
+INSERT INTO chirp_reports(id, created_at, chirp_id, reporter_id, reason, details)
+VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetModerationQueue :many
+SELECT
+    chirps.id AS chirp_id,
+    chirps.body,
+    chirps.user_id AS author_id,
+    chirps.moderation_status,
+    COUNT(chirp_reports.id) AS report_count,
+    array_agg(DISTINCT chirp_reports.reason)::text[] AS reasons,
+    MIN(chirp_reports.created_at)::timestamp AS first_reported_at,
+    MAX(chirp_reports.created_at)::timestamp AS last_reported_at
+FROM chirp_reports
+INNER JOIN chirps ON chirp_reports.chirp_id = chirps.id
+WHERE chirp_reports.resolved_at IS NULL
+GROUP BY chirps.id
+ORDER BY report_count DESC, first_reported_at
+LIMIT $1 OFFSET $2;
+
+-- name: GetReportsByChirpID :many
+SELECT * FROM chirp_reports
+WHERE chirp_id = $1
+ORDER BY created_at;
+
+-- name: ResolveChirpReports :execrows
+UPDATE chirp_reports
+SET resolved_at = $1
+WHERE chirp_id = $2 AND resolved_at IS NULL;
@@ -34,4 +34,54 @@ WHERE id = $1 AND user_id = $2;
 -- name: GetChirpsByUserID :many
 SELECT * FROM chirps
 WHERE user_id = $1
-ORDER BY created_at;
\ No newline at end of file
+ORDER BY created_at;
+
+-- name: GetVisibleChirps :many
+SELECT * FROM chirps
+WHERE (
+    moderation_status = 'visible'
+    OR (moderation_status = 'hidden' AND user_id = sqlc.narg(viewer_id))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = chirps.user_id AND blocked_id = sqlc.narg(viewer_id))
+    OR (blocker_id = sqlc.narg(viewer_id) AND blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE muter_id = sqlc.narg(viewer_id) AND muted_id = chirps.user_id
+)
+ORDER BY created_at;
+
+-- name: GetVisibleChirpByID :one
+SELECT * FROM chirps
+WHERE id = sqlc.arg(id)
+AND (
+    moderation_status = 'visible'
+    OR (moderation_status = 'hidden' AND user_id = sqlc.narg(viewer_id))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = chirps.user_id AND blocked_id = sqlc.narg(viewer_id))
+    OR (blocker_id = sqlc.narg(viewer_id) AND blocked_id = chirps.user_id)
+);
+
+-- name: GetVisibleChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+AND (
+    moderation_status = 'visible'
+    OR (moderation_status = 'hidden' AND user_id = sqlc.narg(viewer_id))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = chirps.user_id AND blocked_id = sqlc.narg(viewer_id))
+    OR (blocker_id = sqlc.narg(viewer_id) AND blocked_id = chirps.user_id)
+)
+ORDER BY created_at;
+
+-- name: SetChirpModerationStatus :one
+UPDATE chirps
+SET moderation_status = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
@@ -0,0 +1,71 @@
+-- name: CreateConversation :one
+WITH conversation AS (
+    INSERT INTO conversations(id, direct_key, created_at, updated_at)
+    VALUES (
+        sqlc.arg(id),
+        sqlc.narg(direct_key),
+        sqlc.arg(created_at),
+        sqlc.arg(created_at)
+    )
+    RETURNING *
+), members AS (
+    INSERT INTO conversation_members(conversation_id, user_id, joined_at)
+    SELECT conversation.id, UNNEST(sqlc.arg(member_ids)::UUID[]), conversation.created_at
+    FROM conversation
+)
+SELECT * FROM conversation;
+
+-- name: GetConversationByID :one
+SELECT * FROM conversations
+WHERE id = $1;
+
+-- name: GetConversationByDirectKey :one
+SELECT * FROM conversations
+WHERE direct_key = sqlc.arg(direct_key)::TEXT;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = $1
+WHERE id = $2;
+
+-- name: GetConversationMember :one
+SELECT * FROM conversation_members
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: GetConversationMembers :many
+SELECT * FROM conversation_members
+WHERE conversation_id = $1
+ORDER BY joined_at, user_id;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = $1
+WHERE conversation_id = $2 AND user_id = $3
+AND (last_read_at IS NULL OR last_read_at < $1);
+
+-- name: GetConversationsForUser :many
+SELECT
+    conversations.id,
+    conversations.created_at,
+    conversations.updated_at,
+    ARRAY(
+        SELECT members.user_id FROM conversation_members AS members
+        WHERE members.conversation_id = conversations.id
+        ORDER BY members.joined_at, members.user_id
+    )::UUID[] AS member_ids,
+    (
+        SELECT COUNT(*) FROM messages
+        WHERE messages.conversation_id = conversations.id
+        AND messages.sender_id <> conversation_members.user_id
+        AND (conversation_members.last_read_at IS NULL OR messages.created_at > conversation_members.last_read_at)
+        AND NOT EXISTS (
+            SELECT 1 FROM user_blocks
+            WHERE (blocker_id = messages.sender_id AND blocked_id = conversation_members.user_id)
+            OR (blocker_id = conversation_members.user_id AND blocked_id = messages.sender_id)
+        )
+    ) AS unread_count
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = sqlc.arg(user_id)
+ORDER BY conversations.updated_at DESC, conversations.id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
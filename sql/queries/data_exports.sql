@@ -0,0 +1,38 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports(id, created_at, user_id, status)
+VALUES (
+    $1, $2, $3, 'pending'
+)
+RETURNING *;
+
+-- name: GetLatestDataExportByUserID :one
+SELECT * FROM data_exports
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ClaimPendingDataExport :one
+UPDATE data_exports
+SET status = 'running'
+WHERE id = (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'ready', archive = $1, completed_at = $2, expires_at = $3
+WHERE id = $4;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed', error = $1, completed_at = $2
+WHERE id = $3;
+
+-- name: DeleteExpiredDataExports :exec
+DELETE FROM data_exports
+WHERE expires_at < $1;
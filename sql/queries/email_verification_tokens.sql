@@ -0,0 +1,26 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens(token_hash, created_at, user_id, email, expires_at, used_at)
+VALUES (
+    $1, $2, $3, $4, $5, NULL
+);
+
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = $1
+WHERE token_hash = $2
+AND used_at IS NULL
+AND expires_at > NOW()
+AND (
+    email = (SELECT users.email FROM users WHERE users.id = email_verification_tokens.user_id)
+    OR NOT EXISTS (
+        SELECT 1 FROM email_verification_tokens newer
+        WHERE newer.user_id = email_verification_tokens.user_id
+        AND newer.created_at > email_verification_tokens.created_at
+    )
+)
+RETURNING user_id, email;
+
+-- name: InvalidateEmailVerificationTokensByUserID :exec
+UPDATE email_verification_tokens
+SET used_at = $1
+WHERE user_id = $2 AND used_at IS NULL;
@@ -0,0 +1,61 @@
+-- name: CreateChirpHashtags :exec
+INSERT INTO chirp_hashtags(chirp_id, tag, created_at)
+SELECT sqlc.arg(chirp_id)::uuid, UNNEST(sqlc.arg(tags)::text[]), sqlc.arg(created_at)::timestamp
+ON CONFLICT DO NOTHING;
+
+-- name: CreateChirpMentions :many
+INSERT INTO chirp_mentions(chirp_id, user_id)
+SELECT sqlc.arg(chirp_id)::uuid, id FROM users
+WHERE LOWER(handle) = ANY(sqlc.arg(handles)::text[])
+ON CONFLICT DO NOTHING
+RETURNING user_id;
+
+-- name: GetVisibleChirpsByHashtag :many
+SELECT chirps.* FROM chirps
+INNER JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = sqlc.arg(tag)
+AND (
+    chirps.moderation_status = 'visible'
+    OR (chirps.moderation_status = 'hidden' AND chirps.user_id = sqlc.narg(viewer_id))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = chirps.user_id AND blocked_id = sqlc.narg(viewer_id))
+    OR (blocker_id = sqlc.narg(viewer_id) AND blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE muter_id = sqlc.narg(viewer_id) AND muted_id = chirps.user_id
+)
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: ComputeTrendingHashtags :exec
+INSERT INTO trending_hashtags(time_window, computed_at, rank, tag, chirp_count, author_count)
+SELECT
+    sqlc.arg(time_window)::text,
+    sqlc.arg(computed_at)::timestamp,
+    ROW_NUMBER() OVER (ORDER BY COUNT(DISTINCT chirps.user_id) DESC, COUNT(*) DESC, chirp_hashtags.tag),
+    chirp_hashtags.tag,
+    COUNT(*),
+    COUNT(DISTINCT chirps.user_id)
+FROM chirp_hashtags
+INNER JOIN chirps ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.created_at > sqlc.arg(since)::timestamp
+AND chirps.moderation_status = 'visible'
+GROUP BY chirp_hashtags.tag
+ORDER BY COUNT(DISTINCT chirps.user_id) DESC, COUNT(*) DESC, chirp_hashtags.tag
+LIMIT sqlc.arg(row_limit);
+
+-- name: DeleteStaleTrendingHashtags :exec
+DELETE FROM trending_hashtags
+WHERE time_window = $1 AND computed_at < $2;
+
+-- name: GetTrendingHashtags :many
+SELECT * FROM trending_hashtags
+WHERE time_window = $1
+AND computed_at = (
+    SELECT MAX(computed_at) FROM trending_hashtags
+    WHERE time_window = $1
+)
+ORDER BY rank;
@@ -0,0 +1,36 @@
+-- name: GetLoginFailure :one
+SELECT * FROM login_failures
+WHERE subject = $1;
+
+-- name: IncrementLoginFailures :one
+INSERT INTO login_failures(subject, failures, last_failed_at, blocked_until)
+VALUES (sqlc.arg(subject), 1, sqlc.arg(failed_at), NULL)
+ON CONFLICT (subject) DO UPDATE
+SET failures = CASE
+        WHEN login_failures.last_failed_at < sqlc.arg(reset_before) THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    last_failed_at = sqlc.arg(failed_at)
+RETURNING *;
+
+-- name: SetLoginFailureBlockedUntil :exec
+UPDATE login_failures
+SET blocked_until = $1
+WHERE subject = $2;
+
+-- name: DeleteLoginFailures :exec
+DELETE FROM login_failures
+WHERE subject = $1;
+
+-- name: CreateLoginLockout :exec
+INSERT INTO login_lockouts(
+    id,
+    created_at,
+    subject,
+    ip_address,
+    failures,
+    locked_until
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6
+);
@@ -0,0 +1,28 @@
+-- name: CreateMessage :one
+INSERT INTO messages(id, conversation_id, sender_id, body, created_at)
+VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetMessages :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = messages.sender_id AND blocked_id = sqlc.arg(viewer_id))
+    OR (blocker_id = sqlc.arg(viewer_id) AND blocked_id = messages.sender_id)
+)
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: GetMessagesForUser :many
+SELECT messages.* FROM messages
+JOIN conversation_members ON conversation_members.conversation_id = messages.conversation_id
+WHERE conversation_members.user_id = sqlc.arg(user_id)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = messages.sender_id AND blocked_id = sqlc.arg(user_id))
+    OR (blocker_id = sqlc.arg(user_id) AND blocked_id = messages.sender_id)
+)
+ORDER BY messages.conversation_id, messages.created_at, messages.id;
@@ -0,0 +1,28 @@
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions(id, created_at, moderator_id, target_user_id, chirp_id, action, note)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetModerationActionByID :one
+SELECT * FROM moderation_actions
+WHERE id = $1;
+
+-- name: GetModerationActionsByChirpID :many
+SELECT * FROM moderation_actions
+WHERE chirp_id = $1
+ORDER BY created_at;
+
+-- name: GetModerationActionsByTargetUserID :many
+SELECT
+    moderation_actions.id,
+    moderation_actions.created_at,
+    moderation_actions.chirp_id,
+    moderation_actions.action,
+    moderation_actions.note,
+    moderation_appeals.status AS appeal_status
+FROM moderation_actions
+LEFT JOIN moderation_appeals ON moderation_appeals.action_id = moderation_actions.id
+WHERE moderation_actions.target_user_id = $1
+ORDER BY moderation_actions.created_at DESC;
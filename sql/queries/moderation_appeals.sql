@@ -0,0 +1,28 @@
+-- name: CreateModerationAppeal :one
+INSERT INTO moderation_appeals(id, created_at, action_id, user_id, message)
+VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetPendingModerationAppeals :many
+SELECT
+    moderation_appeals.id,
+    moderation_appeals.created_at,
+    moderation_appeals.action_id,
+    moderation_appeals.user_id,
+    moderation_appeals.message,
+    moderation_actions.action,
+    moderation_actions.chirp_id,
+    moderation_actions.note AS action_note
+FROM moderation_appeals
+INNER JOIN moderation_actions ON moderation_appeals.action_id = moderation_actions.id
+WHERE moderation_appeals.status = 'pending'
+ORDER BY moderation_appeals.created_at
+LIMIT $1 OFFSET $2;
+
+-- name: ResolveModerationAppeal :one
+UPDATE moderation_appeals
+SET status = $1, resolved_at = $2, resolver_id = $3, resolution_note = $4
+WHERE id = $5 AND status = 'pending'
+RETURNING *;
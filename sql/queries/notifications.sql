@@ -0,0 +1,90 @@
+-- name: UpsertNotification :one
+INSERT INTO notifications(id, user_id, type, chirp_id, group_key, created_at, updated_at)
+SELECT
+    sqlc.arg(id)::uuid,
+    sqlc.arg(user_id)::uuid,
+    sqlc.arg(type)::text,
+    sqlc.narg(chirp_id)::uuid,
+    sqlc.arg(group_key)::text,
+    sqlc.arg(created_at)::timestamp,
+    sqlc.arg(created_at)::timestamp
+WHERE sqlc.arg(user_id)::uuid <> sqlc.arg(actor_id)::uuid
+AND NOT EXISTS (
+    SELECT 1 FROM notification_preferences
+    WHERE notification_preferences.user_id = sqlc.arg(user_id)
+    AND notification_preferences.type = sqlc.arg(type)
+    AND NOT notification_preferences.enabled
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = sqlc.arg(user_id) AND blocked_id = sqlc.arg(actor_id))
+    OR (blocker_id = sqlc.arg(actor_id) AND blocked_id = sqlc.arg(user_id))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE muter_id = sqlc.arg(user_id) AND muted_id = sqlc.arg(actor_id)
+)
+ON CONFLICT (user_id, group_key) WHERE read_at IS NULL
+DO UPDATE SET updated_at = EXCLUDED.updated_at
+RETURNING id;
+
+-- name: AddNotificationActor :exec
+INSERT INTO notification_actors(notification_id, actor_id, created_at)
+VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (notification_id, actor_id) DO UPDATE
+SET created_at = EXCLUDED.created_at;
+
+-- name: GetNotifications :many
+SELECT
+    notifications.id,
+    notifications.type,
+    notifications.chirp_id,
+    notifications.created_at,
+    notifications.updated_at,
+    notifications.read_at,
+    (
+        SELECT COUNT(*) FROM notification_actors
+        WHERE notification_actors.notification_id = notifications.id
+    ) AS actor_count,
+    ARRAY(
+        SELECT actor_id FROM notification_actors
+        WHERE notification_actors.notification_id = notifications.id
+        ORDER BY notification_actors.created_at DESC
+        LIMIT 3
+    )::uuid[] AS recent_actor_ids
+FROM notifications
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND (NOT sqlc.arg(unread_only)::boolean OR notifications.read_at IS NULL)
+ORDER BY notifications.updated_at DESC, notifications.id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, $1)
+WHERE id = $2 AND user_id = $3;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = $1
+WHERE user_id = $2 AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: UpsertNotificationPreference :exec
+INSERT INTO notification_preferences(user_id, type, enabled)
+VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (user_id, type) DO UPDATE
+SET enabled = EXCLUDED.enabled;
+
+-- name: NotifyNotification :exec
+SELECT pg_notify('notifications', sqlc.arg(payload)::TEXT);
@@ -0,0 +1,13 @@
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes(code_hash, created_at, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: UseOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET used_at = $1
+WHERE code_hash = $2
+AND used_at IS NULL
+AND expires_at > NOW()
+RETURNING *;
@@ -0,0 +1,25 @@
+-- name: GetOAuthConsent :one
+SELECT * FROM oauth_consents
+WHERE user_id = $1 AND client_id = $2;
+
+-- name: UpsertOAuthConsent :exec
+INSERT INTO oauth_consents(user_id, client_id, scopes, created_at, updated_at)
+VALUES (
+    $1, $2, $3, $4, $4
+)
+ON CONFLICT (user_id, client_id) DO UPDATE
+SET scopes = EXCLUDED.scopes, updated_at = EXCLUDED.updated_at;
+
+-- name: GetOAuthConsentsByUserID :many
+SELECT oauth_consents.*, oauth_clients.name AS client_name FROM oauth_consents
+INNER JOIN oauth_clients ON oauth_consents.client_id = oauth_clients.id
+WHERE oauth_consents.user_id = $1
+ORDER BY oauth_consents.created_at;
+
+-- name: DeleteOAuthConsent :execrows
+DELETE FROM oauth_consents
+WHERE user_id = $1 AND client_id = $2;
+
+-- name: DeleteOAuthConsentsByUserID :exec
+DELETE FROM oauth_consents
+WHERE user_id = $1;
@@ -0,0 +1,25 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens(token_hash, created_at, user_id, expires_at, used_at)
+VALUES (
+    $1, $2, $3, $4, NULL
+);
+
+-- name: GetUserFromPasswordResetToken :one
+SELECT users.* FROM users
+INNER JOIN password_reset_tokens ON users.id = password_reset_tokens.user_id
+WHERE password_reset_tokens.token_hash = $1
+AND password_reset_tokens.used_at IS NULL
+AND password_reset_tokens.expires_at > NOW();
+
+-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = $1
+WHERE token_hash = $2
+AND used_at IS NULL
+AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: InvalidatePasswordResetTokensByUserID :exec
+UPDATE password_reset_tokens
+SET used_at = $1
+WHERE user_id = $2 AND used_at IS NULL;
@@ -0,0 +1,18 @@
+-- name: EnsureRateLimitBucket :exec
+INSERT INTO rate_limit_buckets(bucket_key, tokens, updated_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (bucket_key) DO NOTHING;
+
+-- name: GetRateLimitBucketForUpdate :one
+SELECT * FROM rate_limit_buckets
+WHERE bucket_key = $1
+FOR UPDATE;
+
+-- name: UpdateRateLimitBucket :exec
+UPDATE rate_limit_buckets
+SET tokens = $1, updated_at = $2
+WHERE bucket_key = $3;
+
+-- name: DeleteRateLimitBucketsUpdatedBefore :exec
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
@@ -15,4 +15,42 @@ RETURNING *;
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = $1, updated_at = $2
-WHERE user_id=$3;
\ No newline at end of file
+WHERE user_id=$3;
+
+-- name: GetRefreshTokensByUserID :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, client_id, scopes, authorization_code_hash)
+VALUES (
+    $1, $2, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetActiveRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token = $1
+AND revoked_at IS NULL
+AND expires_at > NOW();
+
+-- name: RevokeRefreshTokenByToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE token = $3 AND revoked_at IS NULL;
+
+-- name: RevokeOAuthRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE user_id = $3 AND client_id = $4 AND revoked_at IS NULL;
+
+-- name: RevokeOtherRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE user_id = $3 AND token <> $4 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokensByAuthorizationCode :exec
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE authorization_code_hash = $3 AND revoked_at IS NULL;
@@ -0,0 +1,10 @@
+-- name: CreateSubscriptionEvent :exec
+INSERT INTO subscription_events(id, created_at, user_id, event)
+VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: GetSubscriptionEventsByUserID :many
+SELECT * FROM subscription_events
+WHERE user_id = $1
+ORDER BY created_at;
@@ -0,0 +1,14 @@
+-- name: CreateTOTPRecoveryCode :exec
+INSERT INTO totp_recovery_codes(id, created_at, user_id, code_hash, used_at)
+VALUES (
+    $1, $2, $3, $4, NULL
+);
+
+-- name: DeleteTOTPRecoveryCodesByUserID :exec
+DELETE FROM totp_recovery_codes
+WHERE user_id = $1;
+
+-- name: UseTOTPRecoveryCode :execrows
+UPDATE totp_recovery_codes
+SET used_at = $1
+WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL;
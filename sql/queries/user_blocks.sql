@@ -0,0 +1,22 @@
+-- name: CreateUserBlock :exec
+INSERT INTO user_blocks(blocker_id, blocked_id, created_at)
+VALUES (
+    $1, $2, $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteUserBlock :execrows
+DELETE FROM user_blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: GetBlockedOrBlockingUserIDs :many
+SELECT blocked_id AS user_id FROM user_blocks
+WHERE blocker_id = $1
+UNION
+SELECT blocker_id FROM user_blocks
+WHERE blocked_id = $1;
+
+-- name: CountBlocksWithUsers :one
+SELECT COUNT(*) FROM user_blocks
+WHERE (blocker_id = sqlc.arg(user_id) AND blocked_id = ANY(sqlc.arg(other_ids)::UUID[]))
+OR (blocked_id = sqlc.arg(user_id) AND blocker_id = ANY(sqlc.arg(other_ids)::UUID[]));
@@ -0,0 +1,14 @@
+-- name: CreateUserMute :exec
+INSERT INTO user_mutes(muter_id, muted_id, created_at)
+VALUES (
+    $1, $2, $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteUserMute :execrows
+DELETE FROM user_mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutedUserIDs :many
+SELECT muted_id FROM user_mutes
+WHERE muter_id = $1;
@@ -1,7 +1,7 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6
 )
 RETURNING *;
 
@@ -11,23 +11,112 @@ RETURNING *;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users
-WHERE email = $1;
+WHERE LOWER(email) = LOWER($1);
 
 -- name: GetUserFromRefreshToken :one
 SELECT users.* FROM users
 INNER JOIN refresh_tokens ON users.id = refresh_tokens.user_id
 WHERE refresh_tokens.token = $1
+AND refresh_tokens.client_id IS NULL
 AND refresh_tokens.revoked_at IS NULL
 AND refresh_tokens.expires_at > NOW();
 
--- name: UpdateUserEmailAndPassword :one
+-- name: UpdateUserChirpyRedByUserID :one
 UPDATE users
-SET email = $1, hashed_password = $2, updated_at = $3
-WHERE id=$4
+SET is_chirpy_red = $1
+WHERE id = $2
 RETURNING *;
 
--- name: UpdateUserChirpyRedByUserID :one
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetUserTOTPSecret :exec
 UPDATE users
-SET is_chirpy_red = $1
+SET totp_secret = $1, totp_enabled = false, updated_at = $2
+WHERE id = $3;
+
+-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled = true, totp_last_used_step = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: UpdateUserTOTPLastUsedStep :execrows
+UPDATE users
+SET totp_last_used_step = $1
+WHERE id = $2 AND totp_last_used_step < $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: ConfirmUserEmail :one
+UPDATE users
+SET email = $1, email_verified = true, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(handle) = LOWER(@handle::text);
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = $1, display_name = $2, bio = $3, avatar_url = $4, location = $5, updated_at = $6
+WHERE id = $7
+RETURNING *;
+
+-- name: ScheduleUserDeletion :exec
+UPDATE users
+SET deletion_scheduled_at = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET deletion_scheduled_at = NULL, updated_at = $1
+WHERE id = $2;
+
+-- name: DeleteUsersScheduledBefore :execrows
+DELETE FROM users
+WHERE deletion_scheduled_at IS NOT NULL
+AND deletion_scheduled_at <= $1;
+
+-- name: RehashUserPassword :exec
+UPDATE users
+SET hashed_password = sqlc.arg(new_hash)
+WHERE id = sqlc.arg(id) AND hashed_password = sqlc.arg(old_hash);
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin';
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = $1, suspended_until = $2, suspension_reason = $3, updated_at = $4
+WHERE id = $5
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL, suspended_until = NULL, suspension_reason = '', updated_at = $1
 WHERE id = $2
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE sqlc.arg(query)::text = ''
+OR email ILIKE '%' || sqlc.arg(query) || '%'
+OR handle ILIKE '%' || sqlc.arg(query) || '%'
+ORDER BY created_at
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountUsersByIDs :one
+SELECT COUNT(*) FROM users
+WHERE id = ANY(sqlc.arg(ids)::UUID[]);
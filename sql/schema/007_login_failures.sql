@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE login_failures(
+    subject TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL,
+    blocked_until TIMESTAMP
+);
+
+CREATE TABLE login_lockouts(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    subject TEXT NOT NULL,
+    ip_address TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE login_lockouts;
+DROP TABLE login_failures;
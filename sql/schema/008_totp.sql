@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN totp_secret TEXT,
+ADD COLUMN totp_enabled BOOL NOT NULL DEFAULT false,
+ADD COLUMN totp_last_used_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE totp_recovery_codes(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE totp_recovery_codes;
+
+ALTER TABLE users
+DROP totp_secret,
+DROP totp_enabled,
+DROP totp_last_used_step;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Every user is created with a real hash now; users still holding 'unset'
+-- from 003 can't log in until they reset their password.
+ALTER TABLE users
+ALTER COLUMN hashed_password DROP DEFAULT;
+
+-- +goose Down
+ALTER TABLE users
+ALTER COLUMN hashed_password SET DEFAULT 'unset';
@@ -0,0 +1,47 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN moderation_status TEXT NOT NULL DEFAULT 'visible'
+CHECK (moderation_status IN ('visible', 'hidden', 'removed'));
+
+CREATE TABLE chirp_reports(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    resolved_at TIMESTAMP,
+    UNIQUE(chirp_id, reporter_id)
+);
+
+CREATE INDEX chirp_reports_unresolved_idx ON chirp_reports(chirp_id) WHERE resolved_at IS NULL;
+
+CREATE TABLE moderation_actions(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    target_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX moderation_actions_target_user_id_idx ON moderation_actions(target_user_id);
+
+CREATE TABLE moderation_appeals(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    action_id UUID NOT NULL UNIQUE REFERENCES moderation_actions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    resolved_at TIMESTAMP,
+    resolver_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolution_note TEXT NOT NULL DEFAULT ''
+);
+
+-- +goose Down
+DROP TABLE moderation_appeals;
+DROP TABLE moderation_actions;
+DROP TABLE chirp_reports;
+ALTER TABLE chirps DROP moderation_status;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE chirp_hashtags(
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, tag)
+);
+
+CREATE INDEX chirp_hashtags_tag_created_at_idx ON chirp_hashtags(tag, created_at);
+CREATE INDEX chirp_hashtags_created_at_idx ON chirp_hashtags(created_at);
+
+CREATE TABLE chirp_mentions(
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions(user_id);
+
+CREATE TABLE trending_hashtags(
+    time_window TEXT NOT NULL,
+    computed_at TIMESTAMP NOT NULL,
+    rank INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    chirp_count BIGINT NOT NULL,
+    author_count BIGINT NOT NULL,
+    PRIMARY KEY (time_window, computed_at, rank)
+);
+
+-- +goose Down
+DROP TABLE trending_hashtags;
+DROP TABLE chirp_mentions;
+DROP TABLE chirp_hashtags;
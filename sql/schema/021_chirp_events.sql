@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE chirp_events(
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL CHECK (type IN ('chirp_created', 'chirp_deleted')),
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX chirp_events_created_at_idx ON chirp_events(created_at);
+
+-- +goose Down
+DROP TABLE chirp_events;
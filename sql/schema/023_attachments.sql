@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE attachments(
+    id UUID PRIMARY KEY,
+    -- user_id and chirp_id outlive the user and chirp as NULL, so that the
+    -- purge job still finds the row and deletes the stored file.
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX attachments_chirp_id_idx ON attachments(chirp_id);
+CREATE INDEX attachments_unattached_created_at_idx ON attachments(created_at) WHERE chirp_id IS NULL;
+
+-- +goose Down
+DROP TABLE attachments;
@@ -0,0 +1,32 @@
+-- +goose Up
+ALTER TABLE attachments
+ADD COLUMN status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'ready', 'failed')),
+ADD COLUMN width INTEGER,
+ADD COLUMN height INTEGER,
+ADD COLUMN blurhash TEXT,
+ADD COLUMN error TEXT,
+ADD COLUMN processed_at TIMESTAMP;
+
+CREATE INDEX attachments_pending_created_at_idx ON attachments(created_at) WHERE status = 'pending';
+
+CREATE TABLE attachment_variants(
+    attachment_id UUID NOT NULL REFERENCES attachments(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size BIGINT NOT NULL,
+    PRIMARY KEY (attachment_id, name)
+);
+
+-- +goose Down
+DROP TABLE attachment_variants;
+
+ALTER TABLE attachments
+DROP status,
+DROP width,
+DROP height,
+DROP blurhash,
+DROP error,
+DROP processed_at;
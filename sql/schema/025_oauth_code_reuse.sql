@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ADD COLUMN authorization_code_hash TEXT;
+
+CREATE INDEX refresh_tokens_authorization_code_hash_idx ON refresh_tokens(authorization_code_hash) WHERE authorization_code_hash IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP authorization_code_hash;
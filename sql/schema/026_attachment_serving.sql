@@ -0,0 +1,11 @@
+-- +goose Up
+-- attached_at tells attachments whose chirp was deleted, and so are no
+-- longer served, from uploads that were never attached.
+ALTER TABLE attachments
+ADD COLUMN attached_at TIMESTAMP;
+
+UPDATE attachments SET attached_at = created_at WHERE chirp_id IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE attachments
+DROP attached_at;
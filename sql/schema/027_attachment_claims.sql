@@ -0,0 +1,17 @@
+-- +goose Up
+-- claimed_at lets another worker take over an attachment whose processing
+-- was cut short, e.g. by a crash; attempts stops it from doing so forever.
+ALTER TABLE attachments
+ADD COLUMN claimed_at TIMESTAMP,
+ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+
+DROP INDEX attachments_pending_created_at_idx;
+CREATE INDEX attachments_unprocessed_created_at_idx ON attachments(created_at) WHERE status IN ('pending', 'processing');
+
+-- +goose Down
+DROP INDEX attachments_unprocessed_created_at_idx;
+CREATE INDEX attachments_pending_created_at_idx ON attachments(created_at) WHERE status = 'pending';
+
+ALTER TABLE attachments
+DROP claimed_at,
+DROP attempts;